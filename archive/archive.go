@@ -0,0 +1,125 @@
+package archive
+
+import (
+	"clerk_trades/clerk"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+const DIR_ARCHIVE = "archive"
+
+// Item describes an archived filing.
+type Item struct {
+	DocID   string    `json:"DocID"`
+	Year    int       `json:"Year"`
+	URL     string    `json:"URL"`
+	Path    string    `json:"Path"` // relative to the archive directory
+	SHA256  string    `json:"SHA256"`
+	Size    int64     `json:"Size"`
+	Fetched time.Time `json:"Fetched"`
+}
+
+// Manifest records the archived filings. It is shared by all commands, so
+// it must see the items other processes set.
+type Manifest interface {
+	Item(docID string) (Item, bool)
+	SetItem(item Item) error
+	Items() ([]Item, error)
+}
+
+// Archive keeps every downloaded filing on disk under <year>/<docid>.pdf,
+// with a manifest of their hashes. Files are checked against their hash when
+// read, so a damaged file is downloaded again.
+//
+// Files are not stored under their hash: reports are looked up by DocID,
+// and a filing the Clerk replaces under the same DocID replaces the old
+// file instead of leaving it behind. The hash in the manifest addresses the
+// content, so extraction results cached by it stay valid for the file.
+type Archive struct {
+	dir      string
+	manifest Manifest
+}
+
+// Open opens the archive in dir with manifest, creating the directory if
+// needed.
+func Open(dir string, manifest Manifest) (*Archive, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create archive: %v", err)
+	}
+	return &Archive{dir: dir, manifest: manifest}, nil
+}
+
+// Get returns the archived PDF of a report, if it is archived and intact.
+func (a *Archive) Get(r clerk.Report) ([]byte, bool) {
+	item, ok := a.manifest.Item(r.DocID)
+	if !ok {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(filepath.Join(a.dir, item.Path))
+	if err != nil || Hash(data) != item.SHA256 {
+		return nil, false
+	}
+	return data, true
+}
+
+// Put archives the PDF of a report and records it in the manifest.
+func (a *Archive) Put(r clerk.Report, data []byte) error {
+	if r.DocID == "" {
+		return fmt.Errorf("report %s has no DocID", r.URL)
+	}
+
+	year := "unknown"
+	if r.Year > 0 {
+		year = strconv.Itoa(r.Year)
+	}
+	path := filepath.Join(year, r.DocID+".pdf")
+
+	if err := os.MkdirAll(filepath.Join(a.dir, year), 0755); err != nil {
+		return fmt.Errorf("failed to create archive directory: %v", err)
+	}
+	tmp := filepath.Join(a.dir, path+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to archive report %s: %v", r.DocID, err)
+	}
+	if err := os.Rename(tmp, filepath.Join(a.dir, path)); err != nil {
+		return fmt.Errorf("failed to archive report %s: %v", r.DocID, err)
+	}
+
+	return a.manifest.SetItem(Item{
+		DocID:   r.DocID,
+		Year:    r.Year,
+		URL:     r.URL,
+		Path:    path,
+		SHA256:  Hash(data),
+		Size:    int64(len(data)),
+		Fetched: time.Now(),
+	})
+}
+
+// Check checks every file of the manifest against its hash. It returns the
+// items of the manifest, ordered by DocID, and the ones whose file is
+// missing or damaged.
+func (a *Archive) Check() (items, damaged []Item, err error) {
+	if items, err = a.manifest.Items(); err != nil {
+		return nil, nil, err
+	}
+	for _, item := range items {
+		data, err := os.ReadFile(filepath.Join(a.dir, item.Path))
+		if err != nil || Hash(data) != item.SHA256 {
+			damaged = append(damaged, item)
+		}
+	}
+	return items, damaged, nil
+}
+
+// Hash returns the hex SHA-256 of data.
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
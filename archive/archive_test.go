@@ -0,0 +1,125 @@
+package archive
+
+import (
+	"clerk_trades/clerk"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+)
+
+// memManifest keeps the manifest in memory.
+type memManifest map[string]Item
+
+func (m memManifest) Item(docID string) (Item, bool) {
+	item, ok := m[docID]
+	return item, ok
+}
+
+func (m memManifest) SetItem(item Item) error {
+	m[item.DocID] = item
+	return nil
+}
+
+func (m memManifest) Items() ([]Item, error) {
+	var items []Item
+	for _, item := range m {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].DocID < items[j].DocID })
+	return items, nil
+}
+
+func openTest(t *testing.T) (*Archive, string) {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), DIR_ARCHIVE)
+	a, err := Open(dir, memManifest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, dir
+}
+
+func TestPutAndGet(t *testing.T) {
+	a, dir := openTest(t)
+	r := clerk.NewReport("https://example.com/public_disc/ptr-pdfs/2025/20000001.pdf")
+	if err := a.Put(r, []byte("pdf")); err != nil {
+		t.Fatal(err)
+	}
+
+	item, _ := a.manifest.Item(r.DocID)
+	if item.Path != filepath.Join("2025", "20000001.pdf") || item.SHA256 != Hash([]byte("pdf")) || item.Size != 3 || item.URL != r.URL {
+		t.Errorf("archived as %+v", item)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "2025", "20000001.pdf")); err != nil || string(data) != "pdf" {
+		t.Errorf("file holds %q, %v", data, err)
+	}
+	if data, ok := a.Get(r); !ok || string(data) != "pdf" {
+		t.Errorf("got %q, %v", data, ok)
+	}
+
+	// a report filed again under its DocID replaces the file
+	if err := a.Put(r, []byte("new pdf")); err != nil {
+		t.Fatal(err)
+	}
+	if data, ok := a.Get(r); !ok || string(data) != "new pdf" {
+		t.Errorf("got %q after archiving again", data)
+	}
+
+	if _, ok := a.Get(clerk.NewReport("https://example.com/public_disc/ptr-pdfs/2025/20000002.pdf")); ok {
+		t.Error("got a report that was not archived")
+	}
+}
+
+func TestPutWithoutYear(t *testing.T) {
+	a, dir := openTest(t)
+	r := clerk.Report{URL: "file:///tmp/report.pdf", DocID: "local-0123456789ab"}
+	if err := a.Put(r, []byte("pdf")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "unknown", r.DocID+".pdf")); err != nil {
+		t.Errorf("report without year was not archived in unknown/: %v", err)
+	}
+
+	if err := a.Put(clerk.Report{URL: "file:///tmp/other.pdf"}, []byte("pdf")); err == nil {
+		t.Error("report without DocID was archived")
+	}
+}
+
+func TestDamagedFiles(t *testing.T) {
+	a, dir := openTest(t)
+	var reports []clerk.Report
+	for _, docID := range []string{"20000001", "20000002", "20000003"} {
+		r := clerk.NewReport("https://example.com/public_disc/ptr-pdfs/2025/" + docID + ".pdf")
+		if err := a.Put(r, []byte("pdf "+docID)); err != nil {
+			t.Fatal(err)
+		}
+		reports = append(reports, r)
+	}
+
+	// a changed file and a deleted one are not returned
+	if err := os.WriteFile(filepath.Join(dir, "2025", "20000001.pdf"), []byte("pdf 2000000x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "2025", "20000003.pdf")); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []bool{false, true, false} {
+		if _, ok := a.Get(reports[i]); ok != want {
+			t.Errorf("report %s returned %v, want %v", reports[i].DocID, ok, want)
+		}
+	}
+
+	items, damaged, err := a.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, item := range damaged {
+		got = append(got, item.DocID)
+	}
+	if len(items) != 3 || !slices.Equal(got, []string{"20000001", "20000003"}) {
+		t.Errorf("checked %d items, damaged %v; want 3 items, 20000001 and 20000003 damaged", len(items), got)
+	}
+}
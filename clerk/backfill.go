@@ -0,0 +1,117 @@
+package clerk
+
+import (
+	"clerk_trades/utils"
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const FILE_BACKFILL = ".backfill.json"
+
+// ParseYears parses a year range like "2019-2025" or a single year "2024".
+func ParseYears(input string) (int, int, error) {
+	first, last, found := strings.Cut(input, "-")
+	from, err := strconv.Atoi(strings.TrimSpace(first))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid year range %q", input)
+	}
+	to := from
+	if found {
+		if to, err = strconv.Atoi(strings.TrimSpace(last)); err != nil {
+			return 0, 0, fmt.Errorf("invalid year range %q", input)
+		}
+	}
+	if from < 2008 || to < from {
+		return 0, 0, fmt.Errorf("invalid year range %q", input)
+	}
+	return from, to, nil
+}
+
+// Backfill walks the reports of every filing year from..to and hands the
+// ones not yet known to enqueue before it stores them in FILE_LINKS, page by
+// page. Finished years are kept in FILE_BACKFILL, so an interrupted backfill
+// resumes where it stopped. The current filing years are never finished, as
+// reports are still filed for them. Cancelling ctx stops the backfill after
+// the current page, without finishing its year.
+func Backfill(ctx context.Context, src ReportSource, from, to int, enqueue func([]Report) error) error {
+	known, err := utils.ReadJSON[[]Report](Path(FILE_LINKS))
+	if err != nil {
+		return err
+	}
+	links := make(map[string]bool, len(known))
+	for _, r := range known {
+		links[r.URL] = true
+	}
+
+	done, err := utils.ReadJSON[[]int](Path(FILE_BACKFILL))
+	if err != nil {
+		return err
+	}
+
+	for year := from; year <= to; year++ {
+		if ctx.Err() != nil {
+			log.Printf("stopped backfill before %d. run it again to resume.\n", year)
+			return nil
+		}
+		if slices.Contains(done, year) {
+			if verbose {
+				log.Printf("backfill of %d already done. skipping.\n", year)
+			}
+			continue
+		}
+		log.Printf("backfilling reports of %d.\n", year)
+
+		var added int
+		var saveErr error
+		err := src.Discover(ctx, Query{Year: year}, func(reports []Report) bool {
+			var newReports []Report
+			for _, r := range reports {
+				if !links[r.URL] {
+					newReports = append(newReports, r)
+					links[r.URL] = true
+				}
+			}
+			if len(newReports) == 0 {
+				return true
+			}
+
+			if saveErr = enqueue(newReports); saveErr != nil {
+				return false
+			}
+			known = append(known, newReports...)
+			if saveErr = utils.WriteJSON[[]Report](Path(FILE_LINKS), known); saveErr != nil {
+				return false
+			}
+			added += len(newReports)
+			return ctx.Err() == nil
+		})
+		if ctx.Err() != nil {
+			log.Printf("stopped backfill of %d after %d new reports. run it again to resume.\n", year, added)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("backfill of %d failed: %w", year, err)
+		}
+		if saveErr != nil {
+			return fmt.Errorf("backfill of %d failed: %w", year, saveErr)
+		}
+		if slices.Contains(Years(time.Now()), year) {
+			log.Printf("backfilled %d new reports of %d, which is still open. %s contains %d reports.\n",
+				added, year, FILE_LINKS, len(known))
+			continue
+		}
+
+		done = append(done, year)
+		if err := utils.WriteJSON[[]int](Path(FILE_BACKFILL), done); err != nil {
+			return err
+		}
+		log.Printf("backfilled %d new reports of %d. %s contains %d reports.\n", added, year, FILE_LINKS, len(known))
+	}
+
+	return nil
+}
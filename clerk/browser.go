@@ -0,0 +1,196 @@
+package clerk
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// BrowserSource discovers reports by driving the Clerk search page in a
+// headless Chromium through Playwright.
+type BrowserSource struct {
+	BaseURL string // defaults to URL
+}
+
+func NewBrowserSource(baseURL string) *BrowserSource {
+	if baseURL == "" {
+		baseURL = URL
+	}
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+	return &BrowserSource{BaseURL: baseURL}
+}
+
+func (s *BrowserSource) Discover(ctx context.Context, q Query, fn PageFunc) error {
+	pw, err := playwright.Run()
+	if err != nil {
+		return fmt.Errorf(`failed to start Playwright: %v. install/update with
+go run github.com/playwright-community/playwright-go/cmd/playwright@latest install --with-deps
+or
+go install github.com/playwright-community/playwright-go/cmd/playwright@latest
+playwright install --with-deps`, err)
+	}
+	defer pw.Stop()
+
+	browser, err := pw.Chromium.Launch(playwright.BrowserTypeLaunchOptions{
+		Headless: playwright.Bool(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to launch browser: %v", err)
+	}
+	defer browser.Close()
+
+	page, err := browser.NewPage()
+	if err != nil {
+		return fmt.Errorf("failed to create page: %v", err)
+	}
+
+	_, err = page.Goto(s.BaseURL + SEARCH)
+	if err != nil {
+		return fmt.Errorf("failed to go to URL: %v", err)
+	}
+
+	// select the filing year
+	year := fmt.Sprintf("%d", q.Year)
+	selectYear := page.Locator("#FilingYear")
+	_, err = selectYear.SelectOption(playwright.SelectOptionValues{
+		Values: &[]string{year},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to select Filing Year %s: %v", year, err)
+	}
+
+	// click search form and wait for result table
+	if err := page.Click(`button[aria-label="search button"]`); err != nil {
+		return fmt.Errorf("failed to click search button: %v", err)
+	}
+	if _, err = page.WaitForSelector(`#DataTables_Table_0`, playwright.PageWaitForSelectorOptions{
+		State: playwright.WaitForSelectorStateVisible,
+	}); err != nil {
+		return fmt.Errorf("failed to wait for results table to load: %v", err)
+	}
+
+	// get number of pages
+	lastPaginationButtonText, err := page.Locator(`.paginate_button:not(.ellipsis):not(.next):last-child`).InnerText()
+	if err != nil {
+		return fmt.Errorf("failed to find the last pagination button: %v", err)
+	}
+	pageCount, err := strconv.Atoi(lastPaginationButtonText)
+	if err != nil {
+		return fmt.Errorf("failed to convert page count to integer: %v", err)
+	}
+
+	if verbose {
+		log.Printf("looking through %d pages.\n", pageCount)
+	}
+
+	// scrape links
+	for pageNum := 1; pageNum <= pageCount; pageNum++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// Wait for the results table to be visible before scraping
+		if _, err := page.WaitForSelector(`#DataTables_Table_0`, playwright.PageWaitForSelectorOptions{
+			State: playwright.WaitForSelectorStateVisible,
+		}); err != nil {
+			return fmt.Errorf("failed to wait for results table on page %d: %v", pageNum, err)
+		}
+
+		// Scrape the rows
+		rows, err := page.QuerySelectorAll(`#DataTables_Table_0 tbody tr`)
+		if err != nil {
+			return fmt.Errorf("failed to query table rows on page %d: %v", pageNum, err)
+		}
+
+		var reports []Report
+		for _, row := range rows {
+			linkElement, err := row.QuerySelector(`td.memberName a`)
+			if err != nil {
+				log.Printf("failed to find link in row on page %d: %v", pageNum, err)
+				continue
+			}
+
+			fullName, err := linkElement.InnerText()
+			if err != nil {
+				log.Println("Error extracting fullName:", err)
+				continue
+			}
+
+			href, err := linkElement.GetAttribute("href")
+			if err != nil {
+				log.Printf("failed to get href attribute on page %d: %v", pageNum, err)
+				continue
+			}
+
+			if href == "" || strings.Contains(href, pass) {
+				continue
+			}
+
+			cells, err := row.QuerySelectorAll(`td`)
+			if err != nil {
+				log.Printf("failed to query row cells on page %d: %v", pageNum, err)
+			}
+			var texts []string
+			for _, cell := range cells {
+				text, err := cell.InnerText()
+				if err != nil {
+					log.Printf("failed to read row cell on page %d: %v", pageNum, err)
+				}
+				texts = append(texts, text)
+			}
+
+			reports = append(reports, rowReport(s.BaseURL+href, fullName, texts))
+		}
+
+		if !fn(reports) {
+			break
+		}
+
+		if pageNum >= pageCount {
+			break
+		}
+
+		// get next page data
+		next := pageNum + 1
+		dataDtIdxLocator := page.Locator(fmt.Sprintf(".paginate_button:has-text('%d')", next)).GetByText(fmt.Sprintf("%d", next), playwright.LocatorGetByTextOptions{
+			Exact: playwright.Bool(true),
+		})
+
+		dataDtIdxText, err := dataDtIdxLocator.GetAttribute("data-dt-idx")
+		if err != nil {
+			return fmt.Errorf("failed to get data-dt-idx attribute for page %d button: %v", next, err)
+		}
+
+		nextPageButtonLocator := page.Locator(fmt.Sprintf(".paginate_button[data-dt-idx='%s']", dataDtIdxText))
+		if err := nextPageButtonLocator.Click(playwright.LocatorClickOptions{
+			Timeout: playwright.Float(60000), // 60 seconds timeout
+		}); err != nil {
+			return fmt.Errorf("failed to click next page button on page %d: %v", next, err)
+		}
+	}
+
+	return nil
+}
+
+// rowReport builds the report of a row of the search results from the link
+// to its PDF, the member name and the texts of its cells: name, office,
+// filing year and filing type. The year of the row replaces the one of the
+// link.
+func rowReport(link, name string, cells []string) Report {
+	r := NewReport(link)
+	r.Name = strings.TrimSpace(name)
+	if len(cells) >= 4 {
+		r.Office = strings.TrimSpace(cells[1])
+		if year, err := strconv.Atoi(strings.TrimSpace(cells[2])); err == nil {
+			r.Year = year
+		}
+		r.FilingType = strings.TrimSpace(cells[3])
+	}
+	return r
+}
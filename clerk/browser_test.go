@@ -0,0 +1,24 @@
+package clerk
+
+import "testing"
+
+func TestRowReport(t *testing.T) {
+	link := "https://example.com/public_disc/ptr-pdfs/2025/20026590.pdf"
+	r := rowReport(link, " Pelosi, Hon.. Nancy\n", []string{"Pelosi, Hon.. Nancy", " CA11 ", "2025", "PTR Original\n"})
+	want := Report{URL: link, DocID: "20026590", Name: "Pelosi, Hon.. Nancy", Office: "CA11", Year: 2025, FilingType: "PTR Original"}
+	if r != want {
+		t.Errorf("got %+v\nwant %+v", r, want)
+	}
+
+	// rows without the expected cells keep what the link tells
+	r = rowReport(link, "Pelosi, Nancy", []string{"Pelosi, Nancy"})
+	if r.DocID != "20026590" || r.Year != 2025 || r.Office != "" || r.FilingType != "" {
+		t.Errorf("row without cells: %+v", r)
+	}
+
+	// the year of the row is the filing year, even if the link says otherwise
+	r = rowReport("https://example.com/public_disc/ptr-pdfs/2024/20026590.pdf", "Pelosi, Nancy", []string{"", "CA11", "2025", "PTR Original"})
+	if r.Year != 2025 {
+		t.Errorf("year %d, want the 2025 of the row", r.Year)
+	}
+}
@@ -2,13 +2,11 @@ package clerk
 
 import (
 	"clerk_trades/utils"
-	"fmt"
+	"context"
 	"log"
-	"strconv"
-	"strings"
+	"os"
+	"path/filepath"
 	"time"
-
-	"github.com/playwright-community/playwright-go"
 )
 
 const (
@@ -16,169 +14,110 @@ const (
 	SEARCH      = "FinancialDisclosure#Search"
 	pass        = "financial-pdfs"
 	FILE_LINKS  = "links.json"
-	FILE_BACKUP = ".links.json.backup"
+	FILE_BACKUP = ".links.json.backup" // left behind by the name mode of earlier versions
 )
 
 var verbose bool
 
+// dir holds the files of the package, like FILE_LINKS.
+var dir string
+
 func SetVerbose(v bool) {
 	verbose = v
 }
 
-func SiteCheck(links []string, name string) ([]string, error, bool) {
-	var newLinks []string
-	var loop bool
+// SetDir sets the directory the files of the package are kept in.
+func SetDir(d string) {
+	dir = d
+}
 
-	pw, err := playwright.Run()
-	if err != nil {
-		log.Fatalln(`failed to start Playwright. install/update with
-go run github.com/playwright-community/playwright-go/cmd/playwright@latest install --with-deps
-or
-go install github.com/playwright-community/playwright-go/cmd/playwright@latest
-playwright install --with-deps`)
-	}
-	defer pw.Stop()
+// Path returns the path of file in the directory set with SetDir.
+func Path(file string) string {
+	return filepath.Join(dir, file)
+}
 
-	browser, err := pw.Chromium.Launch(playwright.BrowserTypeLaunchOptions{
-		Headless: playwright.Bool(true),
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to launch browser: %v", err), false
+// Years returns the filing years checked at now: this year, and last year
+// during January.
+func Years(now time.Time) []int {
+	// reports filed early in the year may still be listed under the last one
+	if now.Month() == time.January {
+		return []int{now.Year() - 1, now.Year()}
 	}
-	defer browser.Close()
+	return []int{now.Year()}
+}
 
-	page, err := browser.NewPage()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create page: %v", err), false
-	}
+// SiteCheck discovers the reports of the current filing years from src and
+// returns those not in known.
+func SiteCheck(ctx context.Context, src ReportSource, known []Report) ([]Report, error) {
+	var newReports []Report
 
-	_, err = page.Goto(URL + SEARCH)
-	if err != nil {
-		return nil, fmt.Errorf("failed to go to URL: %v", err), false
+	links := make(map[string]bool, len(known))
+	for _, r := range known {
+		links[r.URL] = true
 	}
 
-	// select the current year
-	thisYear := fmt.Sprintf("%d", time.Now().Year())
-	selectYear := page.Locator("#FilingYear")
-	_, err = selectYear.SelectOption(playwright.SelectOptionValues{
-		Values: &[]string{thisYear},
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to select Filing Year %s: %v", thisYear, err), false
+	for _, year := range Years(time.Now()) {
+		query := Query{
+			Year: year,
+		}
+		err := src.Discover(ctx, query, func(reports []Report) bool {
+			for _, r := range reports {
+				if !links[r.URL] {
+					newReports = append(newReports, r)
+					links[r.URL] = true
+					log.Printf("%s (%s %s) %s\n", r.Name, r.Office, r.FilingType, r.URL)
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// click search form and wait for result table
-	if err := page.Click(`button[aria-label="search button"]`); err != nil {
-		return nil, fmt.Errorf("failed to click search button: %v", err), false
+	return newReports, nil
+}
+
+// SaveReports writes all known reports to FILE_LINKS.
+func SaveReports(reports []Report) error {
+	if err := utils.WriteJSON[[]Report](Path(FILE_LINKS), reports); err != nil {
+		return err
 	}
-	if _, err = page.WaitForSelector(`#DataTables_Table_0`, playwright.PageWaitForSelectorOptions{
-		State: playwright.WaitForSelectorStateVisible,
-	}); err != nil {
-		return nil, fmt.Errorf("failed to wait for results table to load: %v", err), false
+	log.Printf("updated %s. contains %d reports.\n", FILE_LINKS, len(reports))
+	return nil
+}
+
+// RecoverBackup merges the links of a FILE_BACKUP left behind by earlier
+// versions back into FILE_LINKS and removes it.
+func RecoverBackup() error {
+	backup, err := os.Stat(Path(FILE_BACKUP))
+	if err != nil || backup.IsDir() {
+		return nil
 	}
 
-	// get number of pages
-	lastPaginationButtonText, err := page.Locator(`.paginate_button:not(.ellipsis):not(.next):last-child`).InnerText()
+	saved, err := utils.ReadJSON[[]Report](Path(FILE_BACKUP))
 	if err != nil {
-		return nil, fmt.Errorf("failed to find the last pagination button: %v", err), false
+		return err
 	}
-	pageCount, err := strconv.Atoi(lastPaginationButtonText)
+	known, err := utils.ReadJSON[[]Report](Path(FILE_LINKS))
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert page count to integer: %v", err), false
+		return err
 	}
 
-	if verbose {
-		log.Printf("looking through %d pages.\n", pageCount)
+	links := make(map[string]bool, len(saved))
+	for _, r := range saved {
+		links[r.URL] = true
 	}
-
-	// scrape links
-	for pageNum := 1; pageNum <= pageCount; pageNum++ {
-		// Wait for the results table to be visible before scraping
-		if _, err := page.WaitForSelector(`#DataTables_Table_0`, playwright.PageWaitForSelectorOptions{
-			State: playwright.WaitForSelectorStateVisible,
-		}); err != nil {
-			return nil, fmt.Errorf("failed to wait for results table on page %d: %v", pageNum, err), loop
-		}
-
-		// Scrape the rows
-		rows, err := page.QuerySelectorAll(`#DataTables_Table_0 tbody tr`)
-		if err != nil {
-			return nil, fmt.Errorf("failed to query table rows on page %d: %v", pageNum, err), loop
-		}
-
-		for _, row := range rows {
-			linkElement, err := row.QuerySelector(`td.memberName a`)
-			if err != nil {
-				log.Printf("failed to find link in row on page %d: %v", pageNum, err)
-				continue
-			}
-
-			if name != "" {
-				fullName, err := linkElement.InnerText()
-				if err != nil {
-					log.Println("Error extracting fullName:", err)
-					continue
-				}
-				if !strings.Contains(fullName, name) {
-					continue
-				}
-			}
-
-			href, err := linkElement.GetAttribute("href")
-			if err != nil {
-				log.Printf("failed to get href attribute on page %d: %v", pageNum, err)
-				continue
-			}
-
-			if href == "" || strings.Contains(href, pass) {
-				continue
-			}
-
-			if !utils.Contains(links, URL+href) {
-				newLinks = append(newLinks, URL+href)
-				log.Println(URL + href)
-			}
-		}
-
-		if len(newLinks) > 5 {
-			newLinks = newLinks[:5]
-			loop = true
-			break
-		}
-
-		if pageNum >= pageCount {
-			break
-		}
-
-		// get next page data
-		next := pageNum + 1
-		dataDtIdxLocator := page.Locator(fmt.Sprintf(".paginate_button:has-text('%d')", next)).GetByText(fmt.Sprintf("%d", next), playwright.LocatorGetByTextOptions{
-			Exact: playwright.Bool(true),
-		})
-
-		dataDtIdxText, err := dataDtIdxLocator.GetAttribute("data-dt-idx")
-		if err != nil {
-			log.Printf("failed to get data-dt-idx attribute for page %d button: %v", next, err)
-			break
-		}
-
-		nextPageButtonLocator := page.Locator(fmt.Sprintf(".paginate_button[data-dt-idx='%s']", dataDtIdxText))
-		if err := nextPageButtonLocator.Click(playwright.LocatorClickOptions{
-			Timeout: playwright.Float(60000), // 60 seconds timeout
-		}); err != nil {
-			log.Printf("failed to click next page button on page %d: %v", next, err)
-			break
+	for _, r := range known {
+		if !links[r.URL] {
+			saved = append(saved, r)
+			links[r.URL] = true
 		}
 	}
 
-	if len(newLinks) != 0 {
-		links = append(links, newLinks...)
-		err = utils.WriteJSON[[]string](FILE_LINKS, links)
-		if err != nil {
-			return links, err, loop
-		}
-		log.Printf("updated %s. contains %d reports.\n", FILE_LINKS, len(links))
+	if err := SaveReports(saved); err != nil {
+		return err
 	}
-
-	return newLinks, nil, loop
+	log.Printf("recovered %s into %s.\n", FILE_BACKUP, FILE_LINKS)
+	return os.Remove(Path(FILE_BACKUP))
 }
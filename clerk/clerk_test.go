@@ -0,0 +1,169 @@
+package clerk
+
+import (
+	"clerk_trades/utils"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakeSource serves fixed result pages for every year, without a browser.
+type fakeSource struct {
+	pages   [][]Report
+	err     error
+	queries []Query
+}
+
+func (s *fakeSource) Discover(ctx context.Context, q Query, fn PageFunc) error {
+	s.queries = append(s.queries, q)
+	if s.err != nil {
+		return s.err
+	}
+	for _, page := range s.pages {
+		if !fn(page) {
+			break
+		}
+	}
+	return nil
+}
+
+func TestSiteCheck(t *testing.T) {
+	a := NewReport("https://example.com/public_disc/ptr-pdfs/2025/20000001.pdf")
+	b := NewReport("https://example.com/public_disc/ptr-pdfs/2025/20000002.pdf")
+	c := NewReport("https://example.com/public_disc/ptr-pdfs/2025/20000003.pdf")
+	src := &fakeSource{pages: [][]Report{{a, b}, {b, c}}}
+
+	found, err := SiteCheck(context.Background(), src, []Report{a})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var docIDs []string
+	for _, r := range found {
+		docIDs = append(docIDs, r.DocID)
+	}
+	if want := []string{"20000002", "20000003"}; !slices.Equal(docIDs, want) {
+		t.Errorf("found %v, want %v", docIDs, want)
+	}
+
+	years := Years(time.Now())
+	if len(src.queries) != len(years) {
+		t.Fatalf("got %d queries, want one per year of %v", len(src.queries), years)
+	}
+	for i, q := range src.queries {
+		if q.Year != years[i] {
+			t.Errorf("query %d is for %d, want %d", i, q.Year, years[i])
+		}
+	}
+}
+
+func TestSiteCheckError(t *testing.T) {
+	fail := errors.New("site down")
+	if _, err := SiteCheck(context.Background(), &fakeSource{err: fail}, nil); !errors.Is(err, fail) {
+		t.Errorf("got error %v, want %v", err, fail)
+	}
+}
+
+func TestYears(t *testing.T) {
+	tests := []struct {
+		now  time.Time
+		want []int
+	}{
+		{time.Date(2025, time.January, 15, 0, 0, 0, 0, time.UTC), []int{2024, 2025}},
+		{time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC), []int{2025}},
+	}
+	for _, tt := range tests {
+		if got := Years(tt.now); !slices.Equal(got, tt.want) {
+			t.Errorf("Years(%s) = %v, want %v", tt.now.Format(time.DateOnly), got, tt.want)
+		}
+	}
+}
+
+func TestBackfill(t *testing.T) {
+	SetDir(t.TempDir())
+	t.Cleanup(func() { SetDir("") })
+	a := NewReport("https://example.com/public_disc/ptr-pdfs/2019/20000001.pdf")
+	b := NewReport("https://example.com/public_disc/ptr-pdfs/2019/20000002.pdf")
+
+	var queued []Report
+	enqueue := func(reports []Report) error {
+		queued = append(queued, reports...)
+		return nil
+	}
+
+	// a year that fails part way is not done
+	fail := errors.New("pagination failed")
+	if err := Backfill(context.Background(), &fakeSource{err: fail}, 2019, 2019, enqueue); !errors.Is(err, fail) {
+		t.Errorf("got error %v, want %v", err, fail)
+	}
+
+	now := time.Now().Year()
+	src := &fakeSource{pages: [][]Report{{a}, {a, b}}}
+	if err := Backfill(context.Background(), src, 2019, 2019, enqueue); err != nil {
+		t.Fatal(err)
+	}
+	if err := Backfill(context.Background(), src, 2019, now, enqueue); err != nil {
+		t.Fatal(err)
+	}
+	if len(queued) != 2 {
+		t.Errorf("queued %d reports, want 2", len(queued))
+	}
+
+	// finished years are skipped, the current ones are walked again
+	src.queries = nil
+	if err := Backfill(context.Background(), src, 2019, now, enqueue); err != nil {
+		t.Fatal(err)
+	}
+	var walked []int
+	for _, q := range src.queries {
+		walked = append(walked, q.Year)
+	}
+	if want := Years(time.Now()); !slices.Equal(walked, want) {
+		t.Errorf("walked %v again, want %v", walked, want)
+	}
+}
+
+func TestBackfillStops(t *testing.T) {
+	SetDir(t.TempDir())
+	t.Cleanup(func() { SetDir("") })
+	a := NewReport("https://example.com/public_disc/ptr-pdfs/2019/20000001.pdf")
+	b := NewReport("https://example.com/public_disc/ptr-pdfs/2019/20000002.pdf")
+	src := &fakeSource{pages: [][]Report{{a}, {b}}}
+
+	// interrupted after the first page
+	ctx, cancel := context.WithCancel(context.Background())
+	var queued []Report
+	enqueue := func(reports []Report) error {
+		queued = append(queued, reports...)
+		cancel()
+		return nil
+	}
+	if err := Backfill(ctx, src, 2019, 2020, enqueue); err != nil {
+		t.Fatal(err)
+	}
+	if len(queued) != 1 || len(src.queries) != 1 {
+		t.Errorf("queued %d reports of %d years, want 1 report of 2019", len(queued), len(src.queries))
+	}
+	done, err := utils.ReadJSON[[]int](Path(FILE_BACKFILL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != 0 {
+		t.Errorf("stopped backfill finished %v", done)
+	}
+
+	// resumed, the year is walked again and finished
+	if err := Backfill(context.Background(), src, 2019, 2019, func(reports []Report) error {
+		queued = append(queued, reports...)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(queued) != 2 || queued[1].DocID != b.DocID {
+		t.Errorf("queued %v after resuming, want %s", queued, b.DocID)
+	}
+	if done, _ = utils.ReadJSON[[]int](Path(FILE_BACKFILL)); !slices.Equal(done, []int{2019}) {
+		t.Errorf("finished %v, want 2019", done)
+	}
+}
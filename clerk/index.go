@@ -0,0 +1,158 @@
+package clerk
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// IndexSource discovers reports from the yearly {year}FD.zip index the Clerk
+// publishes, using plain HTTP instead of a browser.
+type IndexSource struct {
+	BaseURL string // defaults to URL
+	Client  *http.Client
+}
+
+func NewIndexSource(baseURL string) *IndexSource {
+	if baseURL == "" {
+		baseURL = URL
+	}
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+	return &IndexSource{
+		BaseURL: baseURL,
+		Client:  &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+// indexMember is one <Member> entry of the {year}FD.xml index.
+type indexMember struct {
+	Prefix     string `xml:"Prefix"`
+	Last       string `xml:"Last"`
+	First      string `xml:"First"`
+	Suffix     string `xml:"Suffix"`
+	FilingType string `xml:"FilingType"`
+	StateDst   string `xml:"StateDst"`
+	Year       int    `xml:"Year"`
+	FilingDate string `xml:"FilingDate"`
+	DocID      string `xml:"DocID"`
+}
+
+// name formats the member the way the search results list them.
+func (m indexMember) name() string {
+	name := strings.TrimSpace(m.Last + ", " + m.First)
+	if m.Suffix != "" {
+		name += " " + m.Suffix
+	}
+	return name
+}
+
+type index struct {
+	Members []indexMember `xml:"Member"`
+}
+
+// indexFilingTypes names the filing type codes of Periodic Transaction
+// Reports in the index like the search results do. Other filings, like
+// annual reports (O) and their amendments (A), are not PTRs. The index gives
+// amended PTRs no code of their own, so amendments found in it are
+// recognized from their PDF, see ptr.ReadHeader.
+var indexFilingTypes = map[string]string{
+	"P": "PTR",
+}
+
+func (s *IndexSource) Discover(ctx context.Context, q Query, fn PageFunc) error {
+	idx, err := s.fetchIndex(ctx, q.Year)
+	if err != nil {
+		return err
+	}
+
+	var reports []Report
+	for _, m := range idx.Members {
+		filingType, ok := indexFilingTypes[strings.TrimSpace(m.FilingType)]
+		if !ok || m.DocID == "" {
+			continue
+		}
+
+		year := m.Year
+		if year == 0 {
+			year = q.Year
+		}
+		r := NewReport(fmt.Sprintf("%spublic_disc/ptr-pdfs/%d/%s.pdf", s.BaseURL, year, m.DocID))
+		r.Name = m.name()
+		r.Office = m.StateDst
+		r.Year = year
+		r.FilingType = filingType
+		r.FilingDate = m.FilingDate
+		reports = append(reports, r)
+	}
+
+	if verbose {
+		log.Printf("found %d reports in %dFD index.\n", len(reports), q.Year)
+	}
+
+	fn(reports)
+	return nil
+}
+
+func (s *IndexSource) fetchIndex(ctx context.Context, year int) (*index, error) {
+	link := fmt.Sprintf("%spublic_disc/financial-pdfs/%dFD.zip", s.BaseURL, year)
+	req, err := http.NewRequestWithContext(ctx, "GET", link, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download index: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download index %s: %s", link, resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open index archive: %v", err)
+	}
+
+	name := fmt.Sprintf("%dFD.xml", year)
+	for _, f := range zr.File {
+		if !strings.EqualFold(f.Name, name) {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %v", name, err)
+		}
+		defer rc.Close()
+
+		content, err := io.ReadAll(rc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", name, err)
+		}
+		content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf")) // UTF-8 BOM
+
+		var idx index
+		if err := xml.Unmarshal(content, &idx); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", name, err)
+		}
+		return &idx, nil
+	}
+
+	return nil, fmt.Errorf("%s not found in index archive", name)
+}
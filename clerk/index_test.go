@@ -0,0 +1,106 @@
+package clerk
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const indexXML = "\xef\xbb\xbf" + `<?xml version="1.0" encoding="utf-8"?>
+<FinancialDisclosure>
+  <Member>
+    <Prefix>Hon.</Prefix><Last>Pelosi</Last><First>Nancy</First><Suffix />
+    <FilingType>P</FilingType><StateDst>CA11</StateDst><Year>2025</Year>
+    <FilingDate>1/17/2025</FilingDate><DocID>20026590</DocID>
+  </Member>
+  <Member>
+    <Prefix>Hon.</Prefix><Last>Pelosi</Last><First>Nancy</First><Suffix />
+    <FilingType>O</FilingType><StateDst>CA11</StateDst><Year>2025</Year>
+    <FilingDate>5/15/2025</FilingDate><DocID>10065000</DocID>
+  </Member>
+  <Member>
+    <Prefix>Hon.</Prefix><Last>Pelosi</Last><First>Nancy</First><Suffix />
+    <FilingType>A</FilingType><StateDst>CA11</StateDst><Year>2025</Year>
+    <FilingDate>6/2/2025</FilingDate><DocID>10065100</DocID>
+  </Member>
+  <Member>
+    <Prefix>Hon.</Prefix><Last>Khanna</Last><First>Ro</First><Suffix />
+    <FilingType>P</FilingType><StateDst>CA17</StateDst><Year>2025</Year>
+    <FilingDate>2/3/2025</FilingDate><DocID>20026601</DocID>
+  </Member>
+  <Member>
+    <Prefix /><Last>Doe</Last><First>John</First><Suffix>Jr.</Suffix>
+    <FilingType>P</FilingType><StateDst>TX02</StateDst><Year>2025</Year>
+    <FilingDate>3/1/2025</FilingDate><DocID></DocID>
+  </Member>
+</FinancialDisclosure>`
+
+// indexServer serves the {year}FD.zip index of 2025 like the Clerk site.
+func indexServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("2025FD.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(indexXML)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /public_disc/financial-pdfs/2025FD.zip", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(buf.Bytes())
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestIndexSource(t *testing.T) {
+	srv := indexServer(t)
+	want := []Report{
+		{
+			URL: srv.URL + "/public_disc/ptr-pdfs/2025/20026590.pdf", DocID: "20026590",
+			Name: "Pelosi, Nancy", Office: "CA11", Year: 2025, FilingType: "PTR", FilingDate: "1/17/2025",
+		},
+		{
+			URL: srv.URL + "/public_disc/ptr-pdfs/2025/20026601.pdf", DocID: "20026601",
+			Name: "Khanna, Ro", Office: "CA17", Year: 2025, FilingType: "PTR", FilingDate: "2/3/2025",
+		},
+	}
+
+	var got []Report
+	err := NewIndexSource(srv.URL).Discover(context.Background(), Query{Year: 2025}, func(reports []Report) bool {
+		got = append(got, reports...)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d reports %v, want %d", len(got), got, len(want))
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("report %d:\n got %+v\nwant %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestIndexSourceMissingYear(t *testing.T) {
+	srv := indexServer(t)
+	err := NewIndexSource(srv.URL+"/").Discover(context.Background(), Query{Year: 2024}, func([]Report) bool {
+		t.Error("no reports expected")
+		return true
+	})
+	if err == nil {
+		t.Error("missing index did not fail")
+	}
+}
@@ -0,0 +1,71 @@
+package clerk
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Report describes a single disclosure filing found by a ReportSource,
+// together with the filer details listed next to it.
+type Report struct {
+	URL        string `json:"URL"`
+	DocID      string `json:"DocID"`
+	Name       string `json:"Name,omitempty"`       // member name as listed, "Last, First"
+	Office     string `json:"Office,omitempty"`     // state and district, e.g. "CA11"
+	Year       int    `json:"Year"`                 // filing year
+	FilingType string `json:"FilingType,omitempty"` // e.g. "PTR Original"
+	FilingDate string `json:"FilingDate,omitempty"`
+	Source     string `json:"Source,omitempty"`   // where the report came from; empty for the Clerk site
+	MemberID   string `json:"MemberID,omitempty"` // canonical member ID resolved from Name, see roster
+	Amends     string `json:"Amends,omitempty"`   // DocID of the report this one amends
+}
+
+// Amendment reports whether r amends an earlier report, as its filing type,
+// e.g. "PTR Amendment", or a linked original tells.
+func (r Report) Amendment() bool {
+	return r.Amends != "" || strings.Contains(strings.ToLower(r.FilingType), "amend")
+}
+
+// SourceLocal marks reports ingested from local files.
+const SourceLocal = "local"
+
+// UnmarshalJSON also accepts a plain link string, which is how reports were
+// stored in FILE_LINKS before their metadata was kept.
+func (r *Report) UnmarshalJSON(data []byte) error {
+	var link string
+	if err := json.Unmarshal(data, &link); err == nil {
+		*r = NewReport(link)
+		return nil
+	}
+
+	type report Report
+	return json.Unmarshal(data, (*report)(r))
+}
+
+// NewReport builds a Report from the link to its PDF. DocID and Year are
+// taken from the link path (.../ptr-pdfs/<year>/<docid>.pdf) when present.
+func NewReport(link string) Report {
+	r := Report{URL: link}
+	r.DocID = strings.TrimSuffix(path.Base(link), ".pdf")
+	if year, err := strconv.Atoi(path.Base(path.Dir(link))); err == nil {
+		r.Year = year
+	}
+	return r
+}
+
+// Query narrows down what a ReportSource discovers.
+type Query struct {
+	Year int // filing year to search
+}
+
+// PageFunc receives the reports of one results page. Returning false stops
+// the source from visiting further pages.
+type PageFunc func(reports []Report) bool
+
+// ReportSource discovers Periodic Transaction Reports page by page.
+type ReportSource interface {
+	Discover(ctx context.Context, q Query, fn PageFunc) error
+}
@@ -0,0 +1,82 @@
+package clerk
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNewReport(t *testing.T) {
+	for _, tc := range []struct {
+		link  string
+		docID string
+		year  int
+	}{
+		{"https://disclosures-clerk.house.gov/public_disc/ptr-pdfs/2025/20026590.pdf", "20026590", 2025},
+		{"public_disc/ptr-pdfs/2019/20012345.pdf", "20012345", 2019},
+		{"file:///tmp/pdfs/20026590.pdf", "20026590", 0},
+		{"https://example.com/20026590", "20026590", 0},
+	} {
+		r := NewReport(tc.link)
+		if r.URL != tc.link || r.DocID != tc.docID || r.Year != tc.year {
+			t.Errorf("NewReport(%q) = %+v, want DocID %s and year %d", tc.link, r, tc.docID, tc.year)
+		}
+	}
+}
+
+func TestReportUnmarshalJSON(t *testing.T) {
+	var reports []Report
+	data := `[
+		"https://example.com/public_disc/ptr-pdfs/2024/20024001.pdf",
+		{"URL": "https://example.com/public_disc/ptr-pdfs/2025/20026590.pdf", "DocID": "20026590",
+		 "Name": "Pelosi, Nancy", "Office": "CA11", "Year": 2025, "FilingType": "PTR Original", "FilingDate": "1/17/2025"}
+	]`
+	if err := json.Unmarshal([]byte(data), &reports); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Report{
+		{URL: "https://example.com/public_disc/ptr-pdfs/2024/20024001.pdf", DocID: "20024001", Year: 2024},
+		{
+			URL: "https://example.com/public_disc/ptr-pdfs/2025/20026590.pdf", DocID: "20026590",
+			Name: "Pelosi, Nancy", Office: "CA11", Year: 2025, FilingType: "PTR Original", FilingDate: "1/17/2025",
+		},
+	}
+	if len(reports) != len(want) {
+		t.Fatalf("read %d reports, want %d", len(reports), len(want))
+	}
+	for i := range want {
+		if reports[i] != want[i] {
+			t.Errorf("report %d:\n got %+v\nwant %+v", i, reports[i], want[i])
+		}
+	}
+
+	// reports are written with their metadata and read back the same
+	out, err := json.Marshal(reports[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back Report
+	if err := json.Unmarshal(out, &back); err != nil || back != reports[1] {
+		t.Errorf("read back %+v, %v", back, err)
+	}
+
+	if err := json.Unmarshal([]byte(`42`), &back); err == nil {
+		t.Error("a number was read as report")
+	}
+}
+
+func TestAmendment(t *testing.T) {
+	for _, tc := range []struct {
+		r    Report
+		want bool
+	}{
+		{Report{FilingType: "PTR Original"}, false},
+		{Report{FilingType: "PTR"}, false},
+		{Report{FilingType: "PTR Amendment"}, true},
+		{Report{FilingType: "PTR", Amends: "20026590"}, true},
+	} {
+		if got := tc.r.Amendment(); got != tc.want {
+			t.Errorf("%+v: amendment %v, want %v", tc.r, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,840 @@
+package main
+
+import (
+	"clerk_trades/clerk"
+	"clerk_trades/config"
+	"clerk_trades/names"
+	"clerk_trades/roster"
+	"clerk_trades/store"
+	"clerk_trades/trade"
+	"clerk_trades/utils"
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// command is a subcommand of the program. run parses its own options.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands = []command{
+	{"watch", "Check the Clerk site for new reports on a schedule.", runWatch},
+	{"scan", "Check the Clerk site for new reports once and process them.", runScan},
+	{"list", "Print the trades of the last reports.", runList},
+	{"show", "Print a stored report and its trades.", runShow},
+	{"export", "Write the stored trades as CSV or JSON.", runExport},
+	{"serve", "Serve the stored reports and trades over HTTP.", runServe},
+	{"notify", "E-mail the trades of extracted reports not notified yet.", runNotify},
+	{"reprocess", "Extract the trades of stored reports again.", runReprocess},
+	{"backfill", "Store all reports of a range of filing years.", runBackfill},
+	{"ingest", "Process local PTR PDFs.", runIngest},
+	{"query", "Search the stored trades.", runQuery},
+	{"review", "List, approve or reject trades that failed validation.", runReview},
+	{"stuck", "List reports that failed or stopped moving.", runStuck},
+	{"archive", "Check the archived PDFs against their hashes.", runArchive},
+	{"members", "List members of the roster with their reports and trades.", runMembers},
+	{"late", "List members who disclosed trades late.", runLate},
+	{"config", "Check the config file.", runConfig},
+}
+
+// findCommand returns the command called name, or nil.
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+func runWatch(args []string) error {
+	fs := newFlagSet("watch", "", `Check the Clerk site for new reports now and then every -every hours
+(schedule.every in the config file).
+New reports are queued and processed in batches. Failed reports are retried
+on later checks with an increasing delay. Trades approved with review are
+e-mailed with the next check. With watchlists, every watchlist is notified
+of the trades it watches that it was not sent yet. An interrupt finishes the
+batch in progress before the program exits.
+`)
+	sourceFlags(fs)
+	watchlistFlags(fs)
+	pipelineFlags(fs)
+	fs.StringVar(&cfg.Schedule.Every, "every", cfg.Schedule.Every, "Duration between checks. Minimum 3h (e.g. 24h, 72h). Only accepts\n'h' for hours after the integer.")
+	parseArgs(fs, args, 0, 0)
+
+	if err := setup(); err != nil {
+		return err
+	}
+	update, err := config.ParseHours(cfg.Schedule.Every)
+	if err != nil {
+		return err
+	}
+
+	for _, w := range cfg.Filters.Watchlists {
+		log.Printf("watching %s: %d members, %d tickers.\n", w.Name, len(w.Members), len(w.Tickers))
+	}
+	if n := db.Len(); n > 0 {
+		log.Printf("resuming %d queued reports.\n", n)
+	}
+
+	log.Printf("ticker scheduled to check for new reports every %s.\n", fmt.Sprintf("%.0fh", update.Hours()))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ticker := time.NewTicker(update)
+	defer ticker.Stop()
+
+	check := func() {
+		if err := checkReports(ctx); err != nil && ctx.Err() == nil {
+			log.Println("error:", err)
+		}
+	}
+	check()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("interrupted. shutting down.")
+			return nil
+		case <-ticker.C:
+			check()
+		}
+	}
+}
+
+func runScan(args []string) error {
+	fs := newFlagSet("scan", "", `Check the Clerk site for new reports once. New reports and reports still
+in the queue are processed and watchlists are notified, then the program
+exits.
+`)
+	sourceFlags(fs)
+	watchlistFlags(fs)
+	pipelineFlags(fs)
+	parseArgs(fs, args, 0, 0)
+
+	if err := setup(); err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return checkReports(ctx)
+}
+
+func runList(args []string) error {
+	fs := newFlagSet("list", "", `Print the trades of the last -count reports found by scan or watch.
+Trades already extracted are read from the store, the others are extracted.
+`)
+	pipelineFlags(fs)
+	count := fs.Int("count", 5, "Number of reports to list their trades.")
+	parseArgs(fs, args, 0, 0)
+
+	if *count <= 0 {
+		return fmt.Errorf("-count requires a number greater than 0")
+	}
+	if err := setup(); err != nil {
+		return err
+	}
+	return listTrades(*count)
+}
+
+func runShow(args []string) error {
+	fs := newFlagSet("show", "<docid>", `Print a stored report with its processing state, and all trades extracted
+from it with their review status.
+`)
+	docID := parseArgs(fs, args, 1, 1)[0]
+
+	if err := setup(); err != nil {
+		return err
+	}
+	return showReport(docID)
+}
+
+func runExport(args []string) error {
+	fs := newFlagSet("export", "", `Write the stored trades as CSV or JSON. Only accepted and approved trades
+are written, unless -all is set.
+`)
+	format := fs.String("format", "csv", "Output format: csv or json.")
+	output := fs.String("o", "", "File to write to (default standard output).")
+	all := fs.Bool("all", false, "Include trades waiting for review and rejected trades.")
+	parseArgs(fs, args, 0, 0)
+
+	if *format != "csv" && *format != "json" {
+		return fmt.Errorf("-format requires csv or json")
+	}
+	if err := setup(); err != nil {
+		return err
+	}
+	return exportTrades(*format, *output, *all)
+}
+
+func runServe(args []string) error {
+	fs := newFlagSet("serve", "", `Serve the stored reports and trades as JSON over HTTP:
+  GET /reports            all reports with their processing state
+  GET /reports/{docid}    a report and its trades
+  GET /trades?q=<term>&member=<id>&ticker=<ticker>&since=<date>&until=<date>
+                          accepted trades, optionally matching term, of the
+                          member with Bioguide ID id, of ticker, or made in
+                          the period between the dates (YYYY-MM-DD)
+The store is only read, never written, so it can run next to watch and
+serves its changes as soon as they are made.
+`)
+	addr := fs.String("addr", "localhost:8080", "Address to listen on.")
+	parseArgs(fs, args, 0, 0)
+
+	if err := openLog(); err != nil {
+		return err
+	}
+	return serve(*addr)
+}
+
+func runNotify(args []string) error {
+	fs := newFlagSet("notify", "[<docid>...]", `E-mail the accepted trades that were not sent yet, e.g. after approving
+trades with review, and mark their reports notified. With watchlists, every
+watchlist is e-mailed the trades it watches that it was not sent yet. With
+DocIDs, all trades of those reports are e-mailed again.
+`)
+	watchlistFlags(fs)
+	docIDs := parseArgs(fs, args, 0, math.MaxInt)
+
+	cfg.Notifiers.Email.Enabled = true
+	if err := setup(); err != nil {
+		return err
+	}
+
+	if len(docIDs) > 0 {
+		entries, err := findReports(docIDs)
+		if err != nil {
+			return err
+		}
+		var reports []clerk.Report
+		for _, e := range entries {
+			if !e.HasTrades() {
+				return fmt.Errorf("report %s has no trades extracted", e.DocID)
+			}
+			reports = append(reports, e.Report)
+		}
+		if err := notify("TRADES", acceptedTrades(db.Trades(reports...))); err != nil {
+			return err
+		}
+		log.Printf("trades of %d reports have been e-mailed.\n", len(reports))
+		return nil
+	}
+
+	var reports []clerk.Report
+	for _, e := range db.Entries(store.EntryFilter{States: []store.State{store.Extracted}}) {
+		reports = append(reports, e.Report)
+	}
+	return deliverReports(reports)
+}
+
+func runReprocess(args []string) error {
+	fs := newFlagSet("reprocess", "[<docid>...]", `Extract the trades of stored reports again and replace the stored ones,
+e.g. after changing the extractor or the validation rules. Review decisions
+of the replaced trades are lost.
+`)
+	pipelineFlags(fs)
+	failed := fs.Bool("failed", false, "Reprocess all failed reports, including those that gave up.")
+	fs.BoolVar(&noCache, "no-cache", false, "Do not read or write cached extraction results.")
+	docIDs := parseArgs(fs, args, 0, math.MaxInt)
+
+	if len(docIDs) == 0 && !*failed {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if err := setup(); err != nil {
+		return err
+	}
+
+	found, err := findReports(docIDs)
+	if err != nil {
+		return err
+	}
+	if *failed {
+		found = append(found, db.Entries(store.EntryFilter{States: []store.State{store.Failed}})...)
+	}
+	// a report named and failed, or named twice, is reprocessed once
+	var entries []store.Entry
+	for _, e := range found {
+		if !slices.ContainsFunc(entries, func(o store.Entry) bool { return o.DocID == e.DocID }) {
+			entries = append(entries, e)
+		}
+	}
+	if len(entries) == 0 {
+		log.Println("nothing to reprocess.")
+		return nil
+	}
+
+	log.Printf("reprocessing %d reports.\n", len(entries))
+	return deliverReports(extractReports(entries))
+}
+
+func runBackfill(args []string) error {
+	fs := newFlagSet("backfill", "<years>", `Walk all reports of a range of filing years (e.g. 2019-2025), queue them in
+the store and keep them in links.json. An interrupted backfill resumes with
+the years not finished yet; the current filing year is never finished. The
+queued reports are downloaded, extracted and notified by the next scan or
+watch; the ones no watchlist wants are skipped.
+`)
+	sourceFlags(fs)
+	years := parseArgs(fs, args, 1, 1)[0]
+
+	from, to, err := clerk.ParseYears(years)
+	if err != nil {
+		return err
+	}
+	if err := setup(); err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return clerk.Backfill(ctx, source, from, to, queueReports)
+}
+
+func runIngest(args []string) error {
+	fs := newFlagSet("ingest", "<path>", `Process local PTR PDFs: a file, a glob (quoted, e.g. "pdfs/*.pdf") or a
+directory. They go through the same extraction, validation, storage and
+e-mail as reports from the Clerk site, marked with source "local". Files
+named after the DocID of a stored report process that report instead.
+`)
+	watchlistFlags(fs)
+	pipelineFlags(fs)
+	path := parseArgs(fs, args, 1, 1)[0]
+
+	if err := setup(); err != nil {
+		return err
+	}
+	return ingestFiles(path)
+}
+
+func runQuery(args []string) error {
+	fs := newFlagSet("query", "<term>", `List stored trades of the member term resolves to in the roster (a name or a
+Bioguide ID), or else the ones whose member name, asset or ticker matches term.
+`)
+	term := parseArgs(fs, args, 1, 1)[0]
+
+	if err := setup(); err != nil {
+		return err
+	}
+	return queryTrades(term)
+}
+
+func runReview(args []string) error {
+	fs := newFlagSet("review", "[approve|reject <id>]", `List trades that failed validation. They are not listed or e-mailed until
+approved with 'review approve <id>' ('review reject <id>' drops them).
+Approved trades are e-mailed by notify. Validation rules can be changed in
+rules.json.
+`)
+	positional := parseArgs(fs, args, 0, 2)
+
+	action, id := "list", 0
+	if len(positional) > 0 {
+		action = positional[0]
+		if action != "approve" && action != "reject" || len(positional) != 2 {
+			fs.Usage()
+			os.Exit(2)
+		}
+		n, err := strconv.Atoi(positional[1])
+		if err != nil {
+			return fmt.Errorf("review %s requires a trade ID", action)
+		}
+		id = n
+	}
+
+	if err := setup(); err != nil {
+		return err
+	}
+	return reviewTrades(action, id)
+}
+
+func runStuck(args []string) error {
+	fs := newFlagSet("stuck", "", `List queued reports that failed or stopped moving, with their attempts and
+last error. Failed reports are retried by scan and watch with an increasing
+delay, or at once with reprocess.
+`)
+	parseArgs(fs, args, 0, 0)
+
+	if err := setup(); err != nil {
+		return err
+	}
+	return printStuck()
+}
+
+func runArchive(args []string) error {
+	fs := newFlagSet("archive", "", `Check every PDF of the archive against the SHA-256 recorded when it was
+archived, and list the reports whose file is missing or damaged. Those are
+downloaded again when they are processed next, e.g. with reprocess.
+`)
+	parseArgs(fs, args, 0, 0)
+
+	if err := setup(); err != nil {
+		return err
+	}
+	return checkArchive()
+}
+
+func runMembers(args []string) error {
+	fs := newFlagSet("members", "[import <file>...]", fmt.Sprintf(`List the members of the roster with the number of their stored reports and
+trades. Members are identified by their Bioguide ID. A small roster is
+bundled; members are added to it, or replaced, by roster.json in the storage
+directory. 'members import' creates it from legislators-current.json of
+  %s
+and updates it. Members can be added to it, or given aliases, e.g.:
+  [{"ID": "S001234", "Name": "Jane Smith", "Party": "D", "State": "CA",
+    "District": 12, "Chamber": "House", "Aliases": ["Janet Smith"]}]
+Reports and trades are resolved to members when they are stored; the ones
+stored before their member was known are resolved by 'members import' and
+'members -unresolved'.
+`, roster.LegislatorsURL))
+	party := fs.String("party", "", "Only list members of party, e.g. D or R.")
+	state := fs.String("state", "", "Only list members of state, e.g. CA.")
+	all := fs.Bool("all", false, "Also list members without stored reports.")
+	unresolved := fs.Bool("unresolved", false, "Resolve the stored reports without member again, and list the\nnames that still resolve to no member.")
+	positional := parseArgs(fs, args, 0, math.MaxInt)
+
+	if len(positional) > 0 {
+		if positional[0] != "import" || len(positional) < 2 {
+			fs.Usage()
+			os.Exit(2)
+		}
+		if err := importRoster(positional[1:]); err != nil {
+			return err
+		}
+		if err := setup(); err != nil {
+			return err
+		}
+		return resolveMembers()
+	}
+	if err := setup(); err != nil {
+		return err
+	}
+	if *unresolved {
+		if err := resolveMembers(); err != nil {
+			return err
+		}
+		return printUnresolved()
+	}
+	return printMembers(*party, *state, *all)
+}
+
+func runLate(args []string) error {
+	fs := newFlagSet("late", "", fmt.Sprintf(`List the members who disclosed trades later than the %d days the STOCK Act
+allows, with their number of late trades, and the trades disclosed latest.
+Only trades disclosed in the period between -since and -until are counted;
+trades of reports without filing date are left out.
+`, trade.DisclosureDays))
+	since := fs.String("since", time.Now().AddDate(-1, 0, 0).Format(time.DateOnly), "First disclosure date of the period.")
+	until := fs.String("until", time.Now().Format(time.DateOnly), "Last disclosure date of the period.")
+	top := fs.Int("top", 10, "Number of the latest trades listed.")
+	parseArgs(fs, args, 0, 0)
+
+	from, err := trade.ParseDate(*since)
+	if err != nil {
+		return fmt.Errorf("invalid -since date %q", *since)
+	}
+	to, err := trade.ParseDate(*until)
+	if err != nil {
+		return fmt.Errorf("invalid -until date %q", *until)
+	}
+
+	if err := setup(); err != nil {
+		return err
+	}
+	return printLate(from, to, *top)
+}
+
+func runConfig(args []string) error {
+	fs := newFlagSet("config", "validate", `Check the config file and the environment overrides, and print every
+problem found. Options like -storage are checked as well.
+`)
+	action := parseArgs(fs, args, 1, 1)[0]
+	if action != "validate" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if cfg.Notifiers.Email.Enabled {
+		if err := cfg.MigrateMailGun(); err != nil {
+			return err
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("config %s is invalid:\n%v", configFile, err)
+	}
+	log.Printf("config %s is valid.\n", configFile)
+	return nil
+}
+
+// findReports returns the stored entries of the reports with the given DocIDs.
+func findReports(docIDs []string) ([]store.Entry, error) {
+	var entries []store.Entry
+	for _, id := range docIDs {
+		e, ok := db.Report(id)
+		if !ok {
+			return nil, fmt.Errorf("report %s is not in the store", id)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// showReport prints the stored entry of a report and all its trades.
+func showReport(docID string) error {
+	e, ok := db.Report(docID)
+	if !ok {
+		return fmt.Errorf("report %s is not in the store", docID)
+	}
+
+	output := "\n"
+	output += fmt.Sprintf("Report:   %s\n", e.URL)
+	output += fmt.Sprintf("DocID:    %s\n", e.DocID)
+	if e.Name != "" {
+		output += fmt.Sprintf("Name:     %s\n", e.Name)
+	}
+	if e.Office != "" {
+		output += fmt.Sprintf("Office:   %s\n", e.Office)
+	}
+	if e.Year != 0 {
+		output += fmt.Sprintf("Year:     %d\n", e.Year)
+	}
+	if e.FilingType != "" {
+		output += fmt.Sprintf("Filing:   %s %s\n", e.FilingType, e.FilingDate)
+	}
+	if e.Amends != "" {
+		output += fmt.Sprintf("Amends:   %s\n", e.Amends)
+	}
+	if e.Source != "" {
+		output += fmt.Sprintf("Source:   %s\n", e.Source)
+	}
+	output += fmt.Sprintf("State:    %s\n", e.State)
+	if e.State == store.Failed {
+		output += fmt.Sprintf("Failed:   in %s after %d attempts\n", e.FailedIn, e.Attempts)
+		output += fmt.Sprintf("Error:    %s\n", e.LastError)
+	}
+	output += fmt.Sprintf("Updated:  %s\n", e.Updated.Format(time.DateTime))
+
+	trades := db.Trades(e.Report)
+	for _, t := range trades {
+		output += fmt.Sprintf("\nID:      %d\n", t.ID)
+		output += fmt.Sprintf("Status:  %s\n", t.Status)
+		if t.Restates != 0 {
+			output += fmt.Sprintf("Restates: trade %d\n", t.Restates)
+		}
+		if t.Superseded != "" {
+			output += fmt.Sprintf("Superseded: by report %s\n", t.Superseded)
+		}
+		output += strings.TrimPrefix(trade.PrintTrades([]trade.Trade{t.Trade}), "\n")
+	}
+	log.Printf("report %s with %d trades:\r\n%s", e.DocID, len(trades), output)
+	return nil
+}
+
+// queryTrades prints the stored trades of the member term resolves to, or
+// else the ones whose member name, asset or ticker contains term.
+func queryTrades(term string) error {
+	if m, ok := members.Resolve(term, ""); ok {
+		trades := db.Query(store.TradeFilter{MemberID: m.ID})
+		found := acceptedTrades(trades)
+		trade.SortByDate(found)
+		log.Printf("%d trades found for %s:\r\n%s", len(found), m.Label(), trade.PrintTrades(found))
+		return nil
+	}
+
+	trades := db.Query(store.TradeFilter{Text: term})
+	if len(trades) == 0 {
+		log.Printf("no trades found for %q.\n", term)
+		return nil
+	}
+	found := acceptedTrades(trades)
+	trade.SortByDate(found)
+	log.Printf("%d trades found for %q:\r\n%s", len(found), term, trade.PrintTrades(found))
+	return nil
+}
+
+// reviewTrades lists the trades waiting for review, or records the decision
+// on one of them when action is approve or reject.
+func reviewTrades(action string, id int) error {
+	switch action {
+	case "approve":
+		if err := db.SetStatus(id, store.Approved); err != nil {
+			return err
+		}
+		log.Printf("trade %d approved.\n", id)
+		return nil
+	case "reject":
+		if err := db.SetStatus(id, store.Rejected); err != nil {
+			return err
+		}
+		log.Printf("trade %d rejected.\n", id)
+		return nil
+	}
+
+	trades := db.Query(store.TradeFilter{Status: store.Review})
+	if len(trades) == 0 {
+		log.Println("no trades waiting for review.")
+		return nil
+	}
+
+	output := "\n"
+	for _, t := range trades {
+		output += fmt.Sprintf("ID:      %d\n", t.ID)
+		output += strings.TrimPrefix(trade.PrintTrades([]trade.Trade{t.Trade}), "\n")
+	}
+	log.Printf("%d trades waiting for review:\r\n%s", len(trades), output)
+	return nil
+}
+
+// printMembers lists the members of the roster with their stored reports and
+// trades, optionally only those of party and state.
+func printMembers(party, state string, all bool) error {
+	stats := db.Members()
+
+	var listed int
+	output := "\n"
+	for _, m := range members.Members() {
+		if party != "" && !strings.EqualFold(m.Party, party) || state != "" && !strings.EqualFold(m.State, state) {
+			continue
+		}
+		st := stats[m.ID]
+		if !all && st.Reports == 0 {
+			continue
+		}
+		output += fmt.Sprintf("%-8s %-36s %-6s %4d reports %5d trades", m.ID, m.Label(), m.Chamber, st.Reports, st.Trades)
+		if st.LastFiling != "" {
+			output += fmt.Sprintf("   last filed %s", st.LastFiling)
+		}
+		output += "\n"
+		listed++
+	}
+	if listed == 0 {
+		log.Println("no members found.")
+		return nil
+	}
+	log.Printf("%d members:\r\n%s", listed, output)
+	return nil
+}
+
+// importRoster writes the members of congress-legislators files to the
+// roster. Imported members replace the members with the same ID, the others
+// are kept.
+func importRoster(files []string) error {
+	file := storagePath(roster.FILE_ROSTER)
+	var members []roster.Member
+	if _, err := os.Stat(file); err == nil {
+		if members, err = utils.ReadJSON[[]roster.Member](file); err != nil {
+			return fmt.Errorf("failed to read roster %s: %w", file, err)
+		}
+	}
+
+	for _, f := range files {
+		imported, err := roster.ReadLegislators(f)
+		if err != nil {
+			return err
+		}
+		members = roster.Merge(members, imported)
+		log.Printf("imported %d members of %s.\n", len(imported), f)
+	}
+
+	if err := os.MkdirAll(cfg.Storage.Path, 0755); err != nil {
+		return fmt.Errorf("failed to create storage directory: %v", err)
+	}
+	if err := utils.WriteJSON(file, members); err != nil {
+		return err
+	}
+	log.Printf("%s has %d members.\n", file, len(members))
+	return nil
+}
+
+// resolveMembers resolves the stored reports and trades whose member was
+// not known to the roster when they were stored.
+func resolveMembers() error {
+	n, err := db.ResolveMembers()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Printf("resolved the member of %d stored reports.\n", n)
+	}
+	return nil
+}
+
+// printUnresolved lists the member names of stored reports that resolve to
+// no member of the roster, most frequent first.
+func printUnresolved() error {
+	count := make(map[string]int)
+	for _, e := range db.Entries(store.EntryFilter{Unresolved: true}) {
+		name := e.Name
+		if name == "" {
+			name = "(no name, " + e.DocID + ")"
+		}
+		count[name]++
+	}
+	if len(count) == 0 {
+		log.Println("all reports resolve to a member.")
+		return nil
+	}
+
+	unresolved := make([]string, 0, len(count))
+	for name := range count {
+		unresolved = append(unresolved, name)
+	}
+	sort.Slice(unresolved, func(i, j int) bool {
+		if count[unresolved[i]] != count[unresolved[j]] {
+			return count[unresolved[i]] > count[unresolved[j]]
+		}
+		return unresolved[i] < unresolved[j]
+	})
+
+	output := "\n"
+	for _, name := range unresolved {
+		output += fmt.Sprintf("%4d  %s\n", count[name], name)
+	}
+	log.Printf("%d names resolve to no member. add them to %s:\r\n%s", len(unresolved), roster.FILE_ROSTER, output)
+	return nil
+}
+
+// printLate lists the members with trades disclosed late between from and
+// to, most late trades first, and the top trades disclosed latest.
+func printLate(from, to time.Time, top int) error {
+	trades := db.Query(store.TradeFilter{Accepted: true, DisclosedFrom: from, DisclosedTo: to})
+
+	type lateness struct {
+		label  string
+		trades int
+		late   int
+		worst  int // days
+	}
+	byMember := make(map[string]*lateness)
+	var late []store.Trade
+	for _, t := range trades {
+		lag, ok := t.Lag()
+		if !ok {
+			continue
+		}
+		key, label := t.MemberID, t.Name
+		if m, ok := members.Member(t.MemberID); ok {
+			label = m.Label()
+		} else {
+			key = names.Fold(t.Name)
+		}
+		l := byMember[key]
+		if l == nil {
+			l = &lateness{label: label}
+			byMember[key] = l
+		}
+		l.trades++
+		if t.Late() {
+			l.late++
+			late = append(late, t)
+		}
+		l.worst = max(l.worst, lag)
+	}
+	if len(late) == 0 {
+		log.Printf("no trades disclosed late between %s and %s.\n", from.Format(time.DateOnly), to.Format(time.DateOnly))
+		return nil
+	}
+
+	var offenders []*lateness
+	for _, l := range byMember {
+		if l.late > 0 {
+			offenders = append(offenders, l)
+		}
+	}
+	sort.Slice(offenders, func(i, j int) bool {
+		if offenders[i].late != offenders[j].late {
+			return offenders[i].late > offenders[j].late
+		}
+		return offenders[i].label < offenders[j].label
+	})
+
+	output := "\n"
+	for _, l := range offenders {
+		output += fmt.Sprintf("%-36s %4d of %4d trades late   worst %4d days\n", l.label, l.late, l.trades, l.worst)
+	}
+	log.Printf("%d members disclosed %d trades late between %s and %s:\r\n%s", len(offenders), len(late),
+		from.Format(time.DateOnly), to.Format(time.DateOnly), output)
+
+	sort.SliceStable(late, func(i, j int) bool {
+		a, _ := late[i].Lag()
+		b, _ := late[j].Lag()
+		return a > b
+	})
+	if len(late) > top {
+		late = late[:top]
+	}
+	output = "\n"
+	for _, t := range late {
+		label := t.Name
+		if m, ok := members.Member(t.MemberID); ok {
+			label = m.Label()
+		}
+		output += fmt.Sprintf("%-36s %-10s %-10s %-16s %s\n", label, formatDate(t.TradeDate), formatDate(t.DisclosedDate), t.LagText(), t.Asset)
+	}
+	log.Printf("%d trades disclosed latest:\r\n%s", len(late), output)
+	return nil
+}
+
+// checkArchive checks the archived PDFs and lists the damaged ones.
+func checkArchive() error {
+	items, damaged, err := pdfs.Check()
+	if err != nil {
+		return err
+	}
+	var size int64
+	for _, item := range items {
+		size += item.Size
+	}
+	log.Printf("%d reports archived, %.1f MB.\n", len(items), float64(size)/1e6)
+	if len(damaged) == 0 {
+		log.Println("all archived reports are intact.")
+		return nil
+	}
+
+	output := "\n"
+	for _, item := range damaged {
+		output += fmt.Sprintf("%-12s %s\n", item.DocID, item.Path)
+	}
+	log.Printf("%d archived reports are missing or damaged:\r\n%s", len(damaged), output)
+	return nil
+}
+
+// printStuck lists the reports of the store that failed or stopped moving.
+func printStuck() error {
+	stuck := db.Stuck(time.Now())
+	if len(stuck) == 0 {
+		log.Println("no stuck reports.")
+		return nil
+	}
+
+	output := "\n"
+	for _, e := range stuck {
+		output += fmt.Sprintf("Report:   %s\n", e.URL)
+		if e.Name != "" {
+			output += fmt.Sprintf("Name:     %s\n", e.Name)
+		}
+		output += fmt.Sprintf("State:    %s\n", e.State)
+		if e.State == store.Failed {
+			retry := "gave up"
+			if e.Attempts < store.MaxAttempts {
+				retry = e.NextRetry.Format(time.DateTime)
+			}
+			output += fmt.Sprintf("Failed:   in %s after %d attempts\n", e.FailedIn, e.Attempts)
+			output += fmt.Sprintf("Error:    %s\n", e.LastError)
+			output += fmt.Sprintf("Retry:    %s\n", retry)
+		}
+		output += fmt.Sprintf("Updated:  %s\n\n", e.Updated.Format(time.DateTime))
+	}
+	log.Printf("%d stuck reports:\n%s", len(stuck), output)
+	return nil
+}
@@ -0,0 +1,299 @@
+package config
+
+import (
+	"clerk_trades/clerk"
+	"clerk_trades/email"
+	"clerk_trades/gemini"
+	"clerk_trades/openai"
+	"clerk_trades/watchlist"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FILE_CONFIG = "clerk_trades.yaml"
+
+	// ENV_CONFIG names the environment variable that points to another
+	// config file.
+	ENV_CONFIG = "CLERK_TRADES_CONFIG"
+)
+
+// Config holds every setting of the program. Settings are read from the
+// config file and can be overridden by the environment variable named in
+// their env tag, and by command options.
+type Config struct {
+	Sources   Sources   `yaml:"sources"`
+	Storage   Storage   `yaml:"storage"`
+	Extractor Extractor `yaml:"extractor"`
+	Notifiers Notifiers `yaml:"notifiers"`
+	Schedule  Schedule  `yaml:"schedule"`
+	Filters   Filters   `yaml:"filters"`
+}
+
+// Sources selects where reports are discovered and how they are downloaded.
+type Sources struct {
+	Discovery string `yaml:"discovery" env:"CLERK_TRADES_DISCOVERY"` // browser or index
+	URL       string `yaml:"url" env:"CLERK_TRADES_URL"`             // base URL of the Clerk site
+	Workers   int    `yaml:"workers" env:"CLERK_TRADES_WORKERS"`     // parallel downloads
+}
+
+// Storage sets where the store, the report links, the archive and the cache
+// are kept.
+type Storage struct {
+	Path string `yaml:"path" env:"CLERK_TRADES_STORAGE"`
+}
+
+// Extractor selects the backend for reports whose text layer cannot be parsed.
+type Extractor struct {
+	Backend     string `yaml:"backend" env:"CLERK_TRADES_EXTRACTOR"` // gemini, openai or ptr
+	Concurrency int    `yaml:"concurrency" env:"CLERK_TRADES_CONCURRENCY"`
+	Gemini      Gemini `yaml:"gemini"`
+	OpenAI      OpenAI `yaml:"openai"`
+}
+
+type Gemini struct {
+	APIKey string `yaml:"api_key" env:"GEMINI_API_KEY"`
+	Model  string `yaml:"model" env:"GEMINI_MODEL"`
+}
+
+type OpenAI struct {
+	BaseURL string `yaml:"base_url" env:"OPENAI_BASE_URL"`
+	APIKey  string `yaml:"api_key" env:"OPENAI_API_KEY"`
+	Model   string `yaml:"model" env:"OPENAI_MODEL"`
+}
+
+type Notifiers struct {
+	Email Email `yaml:"email"`
+}
+
+// Email holds the Mailgun settings of the e-mail notifications.
+type Email struct {
+	Enabled bool     `yaml:"enabled" env:"CLERK_TRADES_EMAIL"`
+	APIKey  string   `yaml:"api_key" env:"MAILGUN_API_KEY"`
+	Domain  string   `yaml:"domain" env:"MAILGUN_DOMAIN"`
+	To      []string `yaml:"to" env:"MAILGUN_EMAIL_TO"` // comma separated in the environment
+	Paid    bool     `yaml:"paid" env:"MAILGUN_PAID"`   // send to the mailing list instead of each address
+}
+
+// Schedule sets how often watch checks for new reports and how many queued
+// reports are processed at a time.
+type Schedule struct {
+	Every string `yaml:"every" env:"CLERK_TRADES_EVERY"` // hours, e.g. 24h
+	Batch int    `yaml:"batch" env:"CLERK_TRADES_BATCH"`
+}
+
+// Filters limit which trades are notified. Without watchlists all new
+// trades are notified.
+type Filters struct {
+	Watchlists []watchlist.Watchlist `yaml:"watchlists"`
+}
+
+// Default returns the settings used when there is no config file.
+func Default() Config {
+	return Config{
+		Sources: Sources{
+			Discovery: "browser",
+			URL:       clerk.URL,
+			Workers:   4,
+		},
+		Storage: Storage{Path: "."},
+		Extractor: Extractor{
+			Backend:     "gemini",
+			Concurrency: 3,
+			Gemini:      Gemini{Model: gemini.DefaultModel},
+			OpenAI:      OpenAI{BaseURL: openai.DefaultBaseURL, Model: openai.DefaultModel},
+		},
+		Schedule: Schedule{Every: "24h", Batch: 5},
+	}
+}
+
+// File returns the config file named by ENV_CONFIG, or FILE_CONFIG.
+func File() string {
+	if file := os.Getenv(ENV_CONFIG); file != "" {
+		return file
+	}
+	return FILE_CONFIG
+}
+
+// Load reads the config file over the default settings and applies the
+// environment overrides. A missing file is only an error if required is set.
+func Load(file string, required bool) (Config, error) {
+	c := Default()
+
+	f, err := os.Open(file)
+	switch {
+	case err == nil:
+		dec := yaml.NewDecoder(f)
+		dec.KnownFields(true)
+		err = dec.Decode(&c)
+		f.Close()
+		if err != nil && !errors.Is(err, io.EOF) {
+			return c, fmt.Errorf("failed to read config %s: %w", file, err)
+		}
+	case os.IsNotExist(err) && !required:
+	default:
+		return c, fmt.Errorf("failed to open config %s: %w", file, err)
+	}
+
+	if err := applyEnv(reflect.ValueOf(&c).Elem()); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// placeholders are the values of the mailgun.config shipped with the
+// program, which are no real settings.
+var placeholders = map[string]bool{
+	"mailgun_API_KEY":     true,
+	"your.mailgun.domain": true,
+	"your@address.com":    true,
+}
+
+// MigrateMailGun fills the Mailgun settings that are not set, neither in the
+// config file nor in the environment, from mailgun.config. It is only called
+// when e-mail is enabled, so the deprecated file is ignored otherwise.
+func (c *Config) MigrateMailGun() error {
+	e := &c.Notifiers.Email
+	if e.APIKey != "" && e.Domain != "" && len(e.To) > 0 {
+		return nil
+	}
+	if _, err := os.Stat(email.FILE_CONFIG); err != nil {
+		return nil
+	}
+
+	m, err := email.ReadMailGun(email.FILE_CONFIG)
+	if err != nil {
+		return err
+	}
+	if e.APIKey == "" {
+		e.APIKey = m.APIKey
+	}
+	if e.Domain == "" {
+		e.Domain = m.Domain
+	}
+	if len(e.To) == 0 {
+		e.To = m.EmailTo
+	}
+	e.Paid = e.Paid || m.Paid
+	log.Printf("read Mailgun settings from %s. move them to notifiers.email in %s.\n", email.FILE_CONFIG, FILE_CONFIG)
+	return nil
+}
+
+// applyEnv sets the fields of v that have an env tag from the environment
+// variable named by it, if that is set.
+func applyEnv(v reflect.Value) error {
+	for i := 0; i < v.NumField(); i++ {
+		field, value := v.Type().Field(i), v.Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			if err := applyEnv(value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := field.Tag.Get("env")
+		env, ok := os.LookupEnv(name)
+		if name == "" || !ok {
+			continue
+		}
+
+		switch value.Kind() {
+		case reflect.String:
+			value.SetString(env)
+		case reflect.Int:
+			n, err := strconv.Atoi(env)
+			if err != nil {
+				return fmt.Errorf("%s requires a number: %q", name, env)
+			}
+			value.SetInt(int64(n))
+		case reflect.Bool:
+			b, err := strconv.ParseBool(env)
+			if err != nil {
+				return fmt.Errorf("%s requires true or false: %q", name, env)
+			}
+			value.SetBool(b)
+		case reflect.Slice:
+			var list []string
+			for _, item := range strings.Split(env, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					list = append(list, item)
+				}
+			}
+			value.Set(reflect.ValueOf(list))
+		}
+	}
+	return nil
+}
+
+// Validate returns all problems of the settings joined into one error, or nil.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Sources.Discovery == "browser" || c.Sources.Discovery == "index",
+		"sources.discovery must be browser or index, not %q", c.Sources.Discovery)
+	u, err := url.Parse(c.Sources.URL)
+	check(err == nil && (u.Scheme == "http" || u.Scheme == "https"),
+		"sources.url must be an http(s) URL, not %q", c.Sources.URL)
+	check(c.Sources.Workers > 0, "sources.workers must be greater than 0")
+
+	check(c.Storage.Path != "", "storage.path must not be empty")
+
+	switch c.Extractor.Backend {
+	case "gemini", "ptr":
+	case "openai":
+		u, err := url.Parse(c.Extractor.OpenAI.BaseURL)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https"),
+			"extractor.openai.base_url must be an http(s) URL, not %q", c.Extractor.OpenAI.BaseURL)
+	default:
+		check(false, "extractor.backend must be gemini, openai or ptr, not %q", c.Extractor.Backend)
+	}
+	check(c.Extractor.Concurrency > 0, "extractor.concurrency must be greater than 0")
+
+	if e := c.Notifiers.Email; e.Enabled {
+		check(e.APIKey != "", "notifiers.email.api_key (or MAILGUN_API_KEY) is required to send e-mails")
+		check(e.Domain != "", "notifiers.email.domain (or MAILGUN_DOMAIN) is required to send e-mails")
+		check(len(email.ValidEmails(e.To)) > 0, "notifiers.email.to needs at least one e-mail address")
+		check(!placeholders[e.APIKey], "notifiers.email.api_key is the placeholder %q", e.APIKey)
+		check(!placeholders[e.Domain], "notifiers.email.domain is the placeholder %q", e.Domain)
+		for _, to := range e.To {
+			check(!placeholders[strings.TrimSpace(to)], "notifiers.email.to has the placeholder %q", to)
+		}
+	}
+
+	every, err := ParseHours(c.Schedule.Every)
+	check(err == nil, "schedule.every: %v", err)
+	check(err != nil || every >= 3*time.Hour, "schedule.every must be at least 3h")
+	check(c.Schedule.Batch > 0, "schedule.batch must be greater than 0")
+
+	if err := watchlist.Validate(c.Filters.Watchlists); err != nil {
+		errs = append(errs, fmt.Errorf("filters.watchlists: %v", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// ParseHours parses a duration in whole hours, like 24h.
+func ParseHours(input string) (time.Duration, error) {
+	if strings.HasSuffix(input, "h") {
+		hours := strings.TrimSuffix(input, "h")
+		if n, err := strconv.Atoi(hours); err == nil {
+			return time.Duration(n) * time.Hour, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid duration format %q; only hours (h) are accepted", input)
+}
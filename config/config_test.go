@@ -0,0 +1,220 @@
+package config
+
+import (
+	"clerk_trades/email"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// shipped is the mailgun.config that comes with the program.
+const shipped = `MAILGUN_API_KEY  = mailgun_API_KEY
+MAILGUN_DOMAIN   = your.mailgun.domain
+MAILGUN_EMAIL_TO = your@address.com
+MAILGUN_PAID     = false
+`
+
+func TestMailGunOnlyReadWhenEnabled(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile(email.FILE_CONFIG, []byte(shipped), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Load(FILE_CONFIG, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := c.Notifiers.Email; e.APIKey != "" || e.Domain != "" || len(e.To) > 0 {
+		t.Errorf("loaded %+v from %s", e, email.FILE_CONFIG)
+	}
+	if err := c.Validate(); err != nil {
+		t.Errorf("settings without e-mail are invalid: %v", err)
+	}
+
+	// the placeholders are no settings
+	c.Notifiers.Email.Enabled = true
+	if err := c.MigrateMailGun(); err != nil {
+		t.Fatal(err)
+	}
+	err = c.Validate()
+	for _, placeholder := range []string{"mailgun_API_KEY", "your.mailgun.domain", "your@address.com"} {
+		if err == nil || !strings.Contains(err.Error(), placeholder) {
+			t.Errorf("placeholder %s was accepted: %v", placeholder, err)
+		}
+	}
+}
+
+func TestMigrateMailGun(t *testing.T) {
+	t.Chdir(t.TempDir())
+	err := os.WriteFile(email.FILE_CONFIG, []byte("MAILGUN_API_KEY = key\nMAILGUN_DOMAIN = mg.example.com\nMAILGUN_EMAIL_TO = a@example.com\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// settings of the config file or the environment are kept
+	c := Default()
+	c.Notifiers.Email = Email{Enabled: true, Domain: "example.com"}
+	if err := c.MigrateMailGun(); err != nil {
+		t.Fatal(err)
+	}
+	e := c.Notifiers.Email
+	if e.APIKey != "key" || e.Domain != "example.com" || len(e.To) != 1 || e.To[0] != "a@example.com" {
+		t.Errorf("migrated %+v", e)
+	}
+	if err := c.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), FILE_CONFIG)
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestLoad(t *testing.T) {
+	file := writeConfig(t, `
+sources:
+  discovery: index
+storage:
+  path: data
+extractor:
+  openai:
+    model: llama3
+notifiers:
+  email:
+    to: [a@example.com, b@example.com]
+filters:
+  watchlists:
+    - name: leadership
+      members: [Pelosi]
+`)
+	c, err := Load(file, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Sources.Discovery != "index" || c.Storage.Path != "data" || c.Extractor.OpenAI.Model != "llama3" {
+		t.Errorf("settings of the file were not loaded: %+v", c)
+	}
+	if len(c.Notifiers.Email.To) != 2 || len(c.Filters.Watchlists) != 1 || c.Filters.Watchlists[0].Name != "leadership" {
+		t.Errorf("lists of the file were not loaded: %+v, %+v", c.Notifiers.Email, c.Filters)
+	}
+
+	// settings missing from the file keep their defaults
+	d := Default()
+	if c.Sources.URL != d.Sources.URL || c.Extractor.OpenAI.BaseURL != d.Extractor.OpenAI.BaseURL || c.Schedule != d.Schedule {
+		t.Errorf("defaults were not kept: %+v", c)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), FILE_CONFIG)
+	if _, err := Load(missing, true); err == nil {
+		t.Error("missing required config was accepted")
+	}
+	if c, err := Load(missing, false); err != nil || c.Storage.Path != Default().Storage.Path {
+		t.Errorf("missing optional config: %+v, %v; want the defaults", c, err)
+	}
+	if _, err := Load(writeConfig(t, ""), true); err != nil {
+		t.Errorf("empty config: %v", err)
+	}
+
+	for _, content := range []string{
+		"sources:\n  discovry: index\n", // misspelled key
+		"extractor:\n  concurrency: many\n",
+		"schedule: 24h\n",
+	} {
+		if _, err := Load(writeConfig(t, content), true); err == nil {
+			t.Errorf("invalid config %q was accepted", content)
+		}
+	}
+}
+
+func TestEnvOverrides(t *testing.T) {
+	file := writeConfig(t, "sources:\n  workers: 2\nnotifiers:\n  email:\n    to: [file@example.com]\n")
+	t.Setenv("CLERK_TRADES_WORKERS", "8")
+	t.Setenv("CLERK_TRADES_EMAIL", "true")
+	t.Setenv("MAILGUN_EMAIL_TO", "a@example.com, b@example.com,")
+	t.Setenv("OPENAI_MODEL", "llama3")
+
+	c, err := Load(file, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Sources.Workers != 8 || !c.Notifiers.Email.Enabled || c.Extractor.OpenAI.Model != "llama3" {
+		t.Errorf("environment did not override the settings: %+v", c)
+	}
+	if to := c.Notifiers.Email.To; !slices.Equal(to, []string{"a@example.com", "b@example.com"}) {
+		t.Errorf("MAILGUN_EMAIL_TO was split to %q", to)
+	}
+
+	for name, value := range map[string]string{
+		"CLERK_TRADES_WORKERS": "many",
+		"CLERK_TRADES_EMAIL":   "maybe",
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+			if _, err := Load(file, true); err == nil || !strings.Contains(err.Error(), name) {
+				t.Errorf("invalid %s=%s returned %v", name, value, err)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Errorf("default settings are invalid: %v", err)
+	}
+
+	for _, tc := range []struct {
+		change func(c *Config)
+		want   string
+	}{
+		{func(c *Config) { c.Sources.Discovery = "api" }, "sources.discovery"},
+		{func(c *Config) { c.Sources.URL = "ftp://example.com" }, "sources.url"},
+		{func(c *Config) { c.Sources.Workers = 0 }, "sources.workers"},
+		{func(c *Config) { c.Storage.Path = "" }, "storage.path"},
+		{func(c *Config) { c.Extractor.Backend = "claude" }, "extractor.backend"},
+		{func(c *Config) { c.Extractor.Backend, c.Extractor.OpenAI.BaseURL = "openai", "localhost" }, "extractor.openai.base_url"},
+		{func(c *Config) { c.Extractor.Concurrency = 0 }, "extractor.concurrency"},
+		{func(c *Config) { c.Notifiers.Email.Enabled = true }, "notifiers.email.api_key"},
+		{func(c *Config) { c.Schedule.Every = "1d" }, "schedule.every"},
+		{func(c *Config) { c.Schedule.Every = "2h" }, "at least 3h"},
+		{func(c *Config) { c.Schedule.Batch = 0 }, "schedule.batch"},
+	} {
+		c := Default()
+		tc.change(&c)
+		if err := c.Validate(); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("want error about %s, got %v", tc.want, err)
+		}
+	}
+
+	// every problem is reported
+	c := Default()
+	c.Sources.Workers, c.Schedule.Batch = 0, 0
+	if err := c.Validate(); err == nil || !strings.Contains(err.Error(), "sources.workers") || !strings.Contains(err.Error(), "schedule.batch") {
+		t.Errorf("not every problem was reported: %v", err)
+	}
+}
+
+func TestParseHours(t *testing.T) {
+	for input, want := range map[string]time.Duration{
+		"24h": 24 * time.Hour,
+		"3h":  3 * time.Hour,
+	} {
+		if got, err := ParseHours(input); err != nil || got != want {
+			t.Errorf("ParseHours(%q) = %s, %v; want %s", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"", "24", "1d", "1.5h", "90m", "h"} {
+		if _, err := ParseHours(input); err == nil {
+			t.Errorf("ParseHours(%q) was accepted", input)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package download
+
+import (
+	"clerk_trades/clerk"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const userAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/117.0.5938.62 Safari/537.36"
+
+var verbose bool
+
+func SetVerbose(v bool) {
+	verbose = v
+}
+
+// Downloader fetches report PDFs with a bounded number of workers. Requests
+// to the same host are spaced by HostInterval, and failed requests (network
+// errors, 5xx and 429) are retried with an exponential backoff.
+type Downloader struct {
+	Workers      int           // concurrent downloads
+	Timeout      time.Duration // per request
+	Retries      int           // retries after the first attempt
+	Backoff      time.Duration // delay before the first retry, doubled every retry
+	HostInterval time.Duration // minimum time between requests to one host
+	Client       *http.Client
+
+	mu    sync.Mutex
+	hosts map[string]time.Time // next free request slot per host
+}
+
+func New(workers int) *Downloader {
+	return &Downloader{
+		Workers:      max(workers, 1),
+		Timeout:      time.Minute,
+		Retries:      3,
+		Backoff:      2 * time.Second,
+		HostInterval: 250 * time.Millisecond,
+		Client:       &http.Client{},
+		hosts:        map[string]time.Time{},
+	}
+}
+
+// Result is the downloaded PDF of a report, or why it could not be fetched.
+type Result struct {
+	Report clerk.Report
+	Data   []byte
+	Err    error
+}
+
+// Fetch downloads the reports and returns their results keyed by report URL.
+func (d *Downloader) Fetch(ctx context.Context, reports []clerk.Report) map[string]Result {
+	jobs := make(chan clerk.Report)
+	results := make(map[string]Result, len(reports))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for i := 0; i < min(d.Workers, len(reports)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range jobs {
+				data, err := d.fetch(ctx, r.URL)
+				mu.Lock()
+				results[r.URL] = Result{Report: r, Data: data, Err: err}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, r := range reports {
+		jobs <- r
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// statusError is a failed response. Retry tells if it is worth retrying.
+type statusError struct {
+	Status     string
+	Retry      bool
+	RetryAfter time.Duration
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("failed to fetch file: %s", e.Status)
+}
+
+func (d *Downloader) fetch(ctx context.Context, link string) ([]byte, error) {
+	delay := d.Backoff
+	for attempt := 0; ; attempt++ {
+		data, err := d.get(ctx, link)
+		if err == nil {
+			return data, nil
+		}
+
+		se, isStatus := err.(*statusError)
+		if attempt >= d.Retries || isStatus && !se.Retry || ctx.Err() != nil {
+			return nil, err
+		}
+
+		wait := delay
+		if isStatus && se.RetryAfter > wait {
+			wait = se.RetryAfter
+		}
+		if verbose {
+			log.Printf("fetching %s failed: %v. retrying in %s.\n", link, err, wait)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		delay *= 2
+	}
+}
+
+func (d *Downloader) get(ctx context.Context, link string) ([]byte, error) {
+	if err := d.wait(ctx, link); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", link, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		se := &statusError{
+			Status: resp.Status,
+			Retry:  resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500,
+		}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			se.RetryAfter = time.Duration(seconds) * time.Second
+		}
+		return nil, se
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// wait blocks until the host of link may be requested again.
+func (d *Downloader) wait(ctx context.Context, link string) error {
+	u, err := url.Parse(link)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	now := time.Now()
+	slot := d.hosts[u.Host]
+	if slot.Before(now) {
+		slot = now
+	}
+	d.hosts[u.Host] = slot.Add(d.HostInterval)
+	d.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Until(slot)):
+		return nil
+	}
+}
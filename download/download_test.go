@@ -0,0 +1,224 @@
+package download
+
+import (
+	"clerk_trades/clerk"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// server answers requests with handler and records when they arrived.
+type server struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []time.Time
+}
+
+func newServer(t *testing.T, handler func(w http.ResponseWriter, r *http.Request, n int)) *server {
+	t.Helper()
+	s := &server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, time.Now())
+		n := len(s.requests)
+		s.mu.Unlock()
+		handler(w, r, n)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *server) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.requests)
+}
+
+// testDownloader returns a downloader with short delays.
+func testDownloader(workers int) *Downloader {
+	d := New(workers)
+	d.Backoff = 10 * time.Millisecond
+	d.HostInterval = 0
+	return d
+}
+
+func fetchOne(d *Downloader, link string) Result {
+	return d.Fetch(context.Background(), []clerk.Report{{URL: link}})[link]
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+	}{
+		{"server error", http.StatusServiceUnavailable},
+		{"too many requests", http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(t, func(w http.ResponseWriter, r *http.Request, n int) {
+				if n <= 2 {
+					w.WriteHeader(tt.status)
+					return
+				}
+				fmt.Fprint(w, "pdf")
+			})
+
+			res := fetchOne(testDownloader(1), s.URL+"/a.pdf")
+			if res.Err != nil || string(res.Data) != "pdf" {
+				t.Errorf("fetched %q, %v", res.Data, res.Err)
+			}
+			if n := s.count(); n != 3 {
+				t.Errorf("sent %d requests, want 3", n)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	s := newServer(t, func(w http.ResponseWriter, r *http.Request, n int) {
+		if n == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, "pdf")
+	})
+
+	start := time.Now()
+	res := fetchOne(testDownloader(1), s.URL+"/a.pdf")
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %s, want the second of Retry-After", elapsed)
+	}
+}
+
+func TestGiveUp(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		requests int
+	}{
+		{"not found", http.StatusNotFound, 1},
+		{"forbidden", http.StatusForbidden, 1},
+		{"server error", http.StatusInternalServerError, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(t, func(w http.ResponseWriter, r *http.Request, n int) {
+				w.WriteHeader(tt.status)
+			})
+
+			d := testDownloader(1)
+			d.Retries = 3
+			res := fetchOne(d, s.URL+"/a.pdf")
+			var se *statusError
+			if !errors.As(res.Err, &se) || se.Status != fmt.Sprintf("%d %s", tt.status, http.StatusText(tt.status)) {
+				t.Errorf("got %v, want status %d", res.Err, tt.status)
+			}
+			if n := s.count(); n != tt.requests {
+				t.Errorf("sent %d requests, want %d", n, tt.requests)
+			}
+		})
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	s := newServer(t, func(w http.ResponseWriter, r *http.Request, n int) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	})
+	defer close(release)
+
+	d := testDownloader(1)
+	d.Timeout = 50 * time.Millisecond
+	d.Retries = 1
+
+	start := time.Now()
+	res := fetchOne(d, s.URL+"/a.pdf")
+	if !errors.Is(res.Err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want %v", res.Err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("gave up after %s", elapsed)
+	}
+	if n := s.count(); n != 2 {
+		t.Errorf("sent %d requests, want 2", n)
+	}
+}
+
+func TestHostInterval(t *testing.T) {
+	s := newServer(t, func(w http.ResponseWriter, r *http.Request, n int) {
+		fmt.Fprint(w, "pdf")
+	})
+
+	d := testDownloader(4)
+	d.HostInterval = 50 * time.Millisecond
+	var reports []clerk.Report
+	for i := range 4 {
+		reports = append(reports, clerk.Report{URL: fmt.Sprintf("%s/%d.pdf", s.URL, i)})
+	}
+	d.Fetch(context.Background(), reports)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.requests) != 4 {
+		t.Fatalf("sent %d requests, want 4", len(s.requests))
+	}
+	sort.Slice(s.requests, func(i, j int) bool { return s.requests[i].Before(s.requests[j]) })
+	for i := 1; i < len(s.requests); i++ {
+		// the timer may fire a little early
+		if gap := s.requests[i].Sub(s.requests[i-1]); gap < d.HostInterval-5*time.Millisecond {
+			t.Errorf("request %d followed %s after the one before, want %s", i+1, gap, d.HostInterval)
+		}
+	}
+}
+
+func TestFetchKeysByURL(t *testing.T) {
+	s := newServer(t, func(w http.ResponseWriter, r *http.Request, n int) {
+		if r.URL.Path == "/missing.pdf" {
+			http.NotFound(w, r)
+			return
+		}
+		// answer out of order
+		time.Sleep(time.Duration(len(r.URL.Path)%3) * 5 * time.Millisecond)
+		fmt.Fprint(w, r.URL.Path)
+	})
+
+	var reports []clerk.Report
+	for i := range 10 {
+		reports = append(reports, clerk.Report{URL: fmt.Sprintf("%s/%d.pdf", s.URL, i*37), DocID: fmt.Sprint(i)})
+	}
+	reports = append(reports, clerk.Report{URL: s.URL + "/missing.pdf", DocID: "missing"})
+
+	results := testDownloader(3).Fetch(context.Background(), reports)
+	if len(results) != len(reports) {
+		t.Fatalf("got %d results, want %d", len(results), len(reports))
+	}
+	for _, r := range reports {
+		res := results[r.URL]
+		if res.Report != r {
+			t.Errorf("result of %s is for report %+v", r.URL, res.Report)
+		}
+		if r.DocID == "missing" {
+			if res.Err == nil {
+				t.Errorf("missing report was fetched")
+			}
+			continue
+		}
+		if want := r.URL[len(s.URL):]; res.Err != nil || string(res.Data) != want {
+			t.Errorf("result of %s is %q, %v, want %q", r.URL, res.Data, res.Err, want)
+		}
+	}
+}
@@ -3,7 +3,7 @@ package email
 import (
 	"bufio"
 	"bytes"
-	"clerk_trades/gemini"
+	"clerk_trades/trade"
 	"clerk_trades/utils"
 	"context"
 	"fmt"
@@ -17,7 +17,9 @@ import (
 	"html/template"
 )
 
-const configFile = "mailgun.config"
+// FILE_CONFIG is the Mailgun settings file of earlier versions. It is still
+// read when the settings are missing from the config file.
+const FILE_CONFIG = "mailgun.config"
 
 type MailGun struct {
 	APIKey  string
@@ -29,15 +31,17 @@ type MailGun struct {
 
 var Mailgun = &MailGun{}
 
-// load mailgun and its settings from config file
-func LoadMailGun() error {
-	file, err := os.Open(configFile)
+// ReadMailGun reads the Mailgun settings from a file of KEY=value lines like
+// mailgun.config. Unknown keys are skipped.
+func ReadMailGun(file string) (*MailGun, error) {
+	f, err := os.Open(file)
 	if err != nil {
-		return fmt.Errorf("failed to open config file: %w", err)
+		return nil, fmt.Errorf("failed to open config file: %w", err)
 	}
-	defer file.Close()
+	defer f.Close()
 
-	scanner := bufio.NewScanner(file)
+	m := &MailGun{}
+	scanner := bufio.NewScanner(f)
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
 		if line == "" || strings.HasPrefix(line, "#") {
@@ -46,7 +50,7 @@ func LoadMailGun() error {
 
 		parts := strings.SplitN(line, "=", 2)
 		if len(parts) != 2 {
-			return fmt.Errorf("invalid line format: %s", line)
+			return nil, fmt.Errorf("invalid line format: %s", line)
 		}
 
 		key := strings.TrimSpace(parts[0])
@@ -54,40 +58,47 @@ func LoadMailGun() error {
 
 		switch key {
 		case "MAILGUN_API_KEY":
-			Mailgun.APIKey = value
+			m.APIKey = value
 		case "MAILGUN_DOMAIN":
-			Mailgun.Domain = value
+			m.Domain = value
 		case "MAILGUN_EMAIL_TO":
-			Mailgun.EmailTo = strings.Split(value, ",")
-			var validEmails []string
-			for _, email := range Mailgun.EmailTo {
-				email = strings.TrimSpace(email)
-				if email != "" && strings.Contains(email, "@") {
-					validEmails = append(validEmails, email)
-				}
-			}
-			Mailgun.EmailTo = validEmails
+			m.EmailTo = ValidEmails(strings.Split(value, ","))
 		case "MAILGUN_PAID":
-			if value == "true" {
-				Mailgun.Paid = true
-			} else {
-				Mailgun.Paid = false
-			}
+			m.Paid = value == "true"
 		default:
-			return fmt.Errorf("unknown key: %s", key)
+			log.Printf("skipping unknown key %s in %s.\n", key, file)
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("failed to read config file: %w", err)
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	return m, nil
+}
+
+// ValidEmails returns the trimmed addresses of emails that look like one.
+func ValidEmails(emails []string) []string {
+	var valid []string
+	for _, email := range emails {
+		email = strings.TrimSpace(email)
+		if email != "" && strings.Contains(email, "@") {
+			valid = append(valid, email)
+		}
 	}
-	if len(Mailgun.EmailTo) == 0 {
+	return valid
+}
+
+// Setup connects to Mailgun with the settings of m and adds its recipients
+// to the mailing list.
+func Setup(m *MailGun) error {
+	if len(m.EmailTo) == 0 {
 		return fmt.Errorf("no emails in to send trade reports to")
 	}
-	if Mailgun.Domain == "" || Mailgun.APIKey == "" {
-		return fmt.Errorf("missing required fields in config")
+	if m.Domain == "" || m.APIKey == "" {
+		return fmt.Errorf("missing required Mailgun settings")
 	}
 
+	Mailgun = m
 	Mailgun.MailgunImpl = mailgun.NewMailgun(Mailgun.Domain, Mailgun.APIKey)
 	return addEmailsToMailingList(Mailgun.EmailTo...)
 }
@@ -169,7 +180,7 @@ func createMailingList(ctx context.Context) error {
 }
 
 // send emails to mailing list (non-paid account)
-func SendHTMLTo(body string) error {
+func SendHTMLTo(subject, body string) error {
 	ctx := context.Background()
 	it := Mailgun.ListMembers("clerk@"+Mailgun.Domain, nil)
 
@@ -178,8 +189,8 @@ func SendHTMLTo(body string) error {
 		for _, member := range members {
 			m := Mailgun.NewMessage(
 				"clerk trades <mailgun@"+Mailgun.Domain+">", // From
-				"TRADES", // Subject
-				"",       // Body
+				subject, // Subject
+				"",      // Body
 			)
 			m.SetHtml(body)
 			m.AddRecipient(member.Address)
@@ -199,13 +210,13 @@ func SendHTMLTo(body string) error {
 }
 
 // send emails to mailing list (paid account)
-func SendHTMLToMailingList(body string) error {
+func SendHTMLToMailingList(subject, body string) error {
 	listAddress := "clerk@" + Mailgun.Domain
 
 	m := Mailgun.NewMessage(
 		"clerk trades <mailgun@"+Mailgun.Domain+">", // From
-		"TRADES", // Subject
-		"",       // Body
+		subject, // Subject
+		"",      // Body
 	)
 	m.SetHtml(body)
 	m.AddRecipient(listAddress)
@@ -219,7 +230,7 @@ func SendHTMLToMailingList(body string) error {
 }
 
 // generate html body
-func GenerateEmailBody(trades []gemini.Trade) (string, error) {
+func GenerateEmailBody(trades []trade.Trade) (string, error) {
 	tmpl := `
 		<!DOCTYPE html>
 		<html lang="en">
@@ -231,6 +242,7 @@ func GenerateEmailBody(trades []gemini.Trade) (string, error) {
 				table { width: 100%; border-collapse: collapse; }
 				th, td { padding: 8px 12px; border: 1px solid #ddd; text-align: left; }
 				th { background-color: #f4f4f4; }
+				td.late { color: #c0392b; font-weight: bold; }
 			</style>
 		</head>
 		<body>
@@ -239,26 +251,36 @@ func GenerateEmailBody(trades []gemini.Trade) (string, error) {
 				<thead>
 					<tr>
 						<th>Name</th>
+						<th>Owner</th>
 						<th>Asset</th>
+						<th>Class</th>
 						<th>Ticker</th>
+						<th>Option</th>
 						<th>Type</th>
 						<th>Date</th>
 						<th>Filed</th>
+						<th>Lag</th>
 						<th>Amount</th>
 						<th>Cap</th>
+						<th>Report</th>
 					</tr>
 				</thead>
 				<tbody>
 					{{range .}}
 					<tr>
 						<td>{{.Name}}</td>
+						<td>{{.Owner}}</td>
 						<td>{{.Asset}}</td>
+						<td>{{.AssetClass}}</td>
 						<td>{{.Ticker}}</td>
+						<td>{{.Option}}</td>
 						<td>{{.Type}}</td>
 						<td>{{.Date}}</td>
 						<td>{{.Filed}}</td>
+						<td{{if .Late}} class="late"{{end}}>{{.LagText}}</td>
 						<td>{{.Amount}}</td>
 						<td>{{.Cap}}</td>
+						<td>{{if webLink .URL}}<a href="{{.URL}}">{{.ReportID}}</a>{{else}}{{.ReportID}}{{end}}</td>
 					</tr>
 					{{end}}
 				</tbody>
@@ -267,7 +289,7 @@ func GenerateEmailBody(trades []gemini.Trade) (string, error) {
 		</html>
 	`
 
-	t, err := template.New("email").Parse(tmpl)
+	t, err := template.New("email").Funcs(template.FuncMap{"webLink": webLink}).Parse(tmpl)
 	if err != nil {
 		return "", fmt.Errorf("error creating email template: %v", err)
 	}
@@ -281,3 +303,9 @@ func GenerateEmailBody(trades []gemini.Trade) (string, error) {
 	emailBody := emailBodyBuffer.String()
 	return emailBody, nil
 }
+
+// webLink reports whether url can be linked in an email. Reports ingested
+// from local files have file:// URLs, which html/template would replace.
+func webLink(url string) bool {
+	return strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "http://")
+}
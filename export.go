@@ -0,0 +1,130 @@
+package main
+
+import (
+	"clerk_trades/store"
+	"clerk_trades/trade"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvHeader names the columns exportTrades writes for every trade.
+var csvHeader = []string{
+	"ID", "Status", "Restates", "ReportID", "MemberID", "Name", "Owner", "Asset",
+	"Ticker", "TickerSource", "CUSIP", "AssetType", "OptionType", "Strike", "Expiration",
+	"Type", "Date", "Filed", "Disclosed", "LagDays", "Late", "Amount", "AmountMin", "AmountMax", "Cap",
+	"Issues", "URL",
+}
+
+// exportTrades writes the stored trades as csv or json to file, or to
+// standard output if file is empty. Only accepted trades are written unless
+// all is set.
+func exportTrades(format, file string, all bool) error {
+	trades := db.Query(store.TradeFilter{Accepted: !all})
+
+	var w io.Writer = os.Stdout
+	if file != "" {
+		f, err := os.Create(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	var err error
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(trades)
+	} else {
+		err = writeCSV(w, trades)
+	}
+	if err != nil {
+		return err
+	}
+
+	if file != "" {
+		log.Printf("exported %d trades to %s.\n", len(trades), file)
+	}
+	return nil
+}
+
+// writeCSV writes trades as CSV with a header row.
+func writeCSV(w io.Writer, trades []store.Trade) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, t := range trades {
+		err := cw.Write([]string{
+			strconv.Itoa(t.ID),
+			string(t.Status),
+			formatInt(int64(t.Restates)),
+			t.ReportID,
+			t.MemberID,
+			t.Name,
+			t.Owner,
+			t.Asset,
+			t.Ticker,
+			string(t.TickerSource),
+			t.CUSIP,
+			t.AssetType,
+			t.OptionType,
+			formatFloat(t.Strike),
+			t.Expiration,
+			string(t.Type),
+			formatDate(t.TradeDate),
+			formatDate(t.FiledDate),
+			formatDate(t.DisclosedDate),
+			formatLag(t.Trade),
+			strconv.FormatBool(t.Late()),
+			t.Amount,
+			formatInt(t.AmountMin),
+			formatInt(t.AmountMax),
+			strconv.FormatBool(t.Cap),
+			strings.Join(slices.Concat(t.ParseErrors, t.Issues), "; "),
+			t.URL,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatDate(d time.Time) string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.Format(time.DateOnly)
+}
+
+func formatLag(t trade.Trade) string {
+	lag, ok := t.Lag()
+	if !ok {
+		return ""
+	}
+	return strconv.Itoa(lag)
+}
+
+func formatInt(n int64) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+func formatFloat(f float64) string {
+	if f == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
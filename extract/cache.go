@@ -0,0 +1,69 @@
+package extract
+
+import (
+	"clerk_trades/clerk"
+	"clerk_trades/trade"
+	"clerk_trades/utils"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+const DIR_CACHE = "cache"
+
+// PromptVersion changes whenever Prompt or Schema change, so cached results
+// of an older prompt are not used.
+var PromptVersion = func() string {
+	sum := sha256.Sum256([]byte(Prompt + fmt.Sprint(Schema)))
+	return hex.EncodeToString(sum[:6])
+}()
+
+// Cache wraps an Extractor and keeps its results on disk, keyed by the
+// SHA-256 of the PDF, the extractor name and PromptVersion. A PDF that was
+// extracted before is answered from disk without calling the extractor.
+type Cache struct {
+	Extractor
+	dir string
+}
+
+func NewCache(ex Extractor, dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %v", err)
+	}
+	return &Cache{Extractor: ex, dir: dir}, nil
+}
+
+// Key returns the cache key of a PDF.
+func (c *Cache) Key(pdf []byte) string {
+	sum := sha256.Sum256(pdf)
+	key := sha256.Sum256([]byte(hex.EncodeToString(sum[:]) + "|" + c.Extractor.Name() + "|" + PromptVersion))
+	return hex.EncodeToString(key[:])
+}
+
+func (c *Cache) Extract(ctx context.Context, pdf []byte, report clerk.Report) ([]trade.Trade, error) {
+	file := filepath.Join(c.dir, c.Key(pdf)+".json")
+
+	if _, err := os.Stat(file); err == nil {
+		trades, err := utils.ReadJSON[[]trade.Trade](file)
+		if err == nil {
+			if verbose {
+				log.Printf("report %s: %d trades from cache.\n", report.URL, len(trades))
+			}
+			return trades, nil
+		}
+		log.Printf("ignoring broken cache entry %s: %v\n", file, err)
+	}
+
+	trades, err := c.Extractor.Extract(ctx, pdf, report)
+	if err != nil {
+		return nil, err
+	}
+	if err := utils.WriteJSON(file, trades); err != nil {
+		log.Printf("failed to cache trades of report %s: %v\n", report.URL, err)
+	}
+	return trades, nil
+}
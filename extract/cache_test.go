@@ -0,0 +1,114 @@
+package extract
+
+import (
+	"clerk_trades/clerk"
+	"clerk_trades/trade"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type countingExtractor struct {
+	name  string
+	calls int
+	err   error
+}
+
+func (c *countingExtractor) Name() string { return c.name }
+
+func (c *countingExtractor) Extract(context.Context, []byte, clerk.Report) ([]trade.Trade, error) {
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	return []trade.Trade{{Name: "Nancy Pelosi", Asset: "NVIDIA Corporation (NVDA) [ST]"}}, nil
+}
+
+func (c *countingExtractor) Close() error { return nil }
+
+func newCache(t *testing.T, ex Extractor, dir string) *Cache {
+	c, err := NewCache(ex, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	ex := &countingExtractor{name: "gemini"}
+	c := newCache(t, ex, dir)
+	pdf := []byte("%PDF-1.7 report")
+
+	for range 2 {
+		trades, err := c.Extract(context.Background(), pdf, clerk.Report{})
+		if err != nil || len(trades) != 1 || trades[0].Name != "Nancy Pelosi" {
+			t.Fatalf("extracted %v, %v", trades, err)
+		}
+	}
+	if ex.calls != 1 {
+		t.Errorf("extractor called %d times, want 1", ex.calls)
+	}
+
+	// a cache opened later on the same directory answers from disk
+	if _, err := newCache(t, ex, dir).Extract(context.Background(), pdf, clerk.Report{}); err != nil || ex.calls != 1 {
+		t.Errorf("extractor called %d times after reopening, %v", ex.calls, err)
+	}
+
+	if _, err := c.Extract(context.Background(), []byte("%PDF-1.7 other report"), clerk.Report{}); err != nil || ex.calls != 2 {
+		t.Errorf("other PDF: extractor called %d times, %v", ex.calls, err)
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	pdf := []byte("%PDF-1.7 report")
+	gemini := newCache(t, &countingExtractor{name: "gemini"}, t.TempDir())
+	claude := newCache(t, &countingExtractor{name: "claude"}, t.TempDir())
+
+	if gemini.Key(pdf) != gemini.Key(pdf) {
+		t.Error("key of the same PDF changed")
+	}
+	if gemini.Key(pdf) == gemini.Key([]byte("%PDF-1.7 other report")) {
+		t.Error("different PDFs have the same key")
+	}
+	if gemini.Key(pdf) == claude.Key(pdf) {
+		t.Error("different extractors have the same key")
+	}
+}
+
+func TestCacheError(t *testing.T) {
+	failed := errors.New("quota exceeded")
+	ex := &countingExtractor{name: "gemini", err: failed}
+	c := newCache(t, ex, t.TempDir())
+	pdf := []byte("%PDF-1.7 report")
+
+	if _, err := c.Extract(context.Background(), pdf, clerk.Report{}); !errors.Is(err, failed) {
+		t.Fatalf("got %v, want %v", err, failed)
+	}
+	ex.err = nil
+	if trades, err := c.Extract(context.Background(), pdf, clerk.Report{}); err != nil || len(trades) != 1 || ex.calls != 2 {
+		t.Errorf("error was cached: extracted %v, %v with %d calls", trades, err, ex.calls)
+	}
+}
+
+func TestCacheBrokenEntry(t *testing.T) {
+	dir := t.TempDir()
+	ex := &countingExtractor{name: "gemini"}
+	c := newCache(t, ex, dir)
+	pdf := []byte("%PDF-1.7 report")
+
+	file := filepath.Join(dir, c.Key(pdf)+".json")
+	if err := os.WriteFile(file, []byte(`[{"Name": "Nancy`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	trades, err := c.Extract(context.Background(), pdf, clerk.Report{})
+	if err != nil || len(trades) != 1 || ex.calls != 1 {
+		t.Fatalf("extracted %v, %v with %d calls", trades, err, ex.calls)
+	}
+	// the broken entry is replaced
+	if _, err := c.Extract(context.Background(), pdf, clerk.Report{}); err != nil || ex.calls != 1 {
+		t.Errorf("broken entry was not replaced: %d calls, %v", ex.calls, err)
+	}
+}
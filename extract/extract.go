@@ -0,0 +1,155 @@
+package extract
+
+import (
+	"clerk_trades/clerk"
+	"clerk_trades/trade"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// Extractor reads the trades of a single report PDF.
+type Extractor interface {
+	// Name identifies the backend and model, e.g. "gemini/gemini-1.5-flash".
+	Name() string
+	Extract(ctx context.Context, pdf []byte, report clerk.Report) ([]trade.Trade, error)
+	Close() error
+}
+
+// Prompt is the instruction every LLM backend extracts trades with.
+const Prompt = `
+It should read data from the PDF file and write data into the JSON array described below with some rules.
+Rule1: Name can be obtained under Filer Information. Input First Name and Last Name only! Dont include "Hon.", "Mrs", "Mr", etc.
+Rule2: in Type field (Transaction Type): if "P" input "Purchase", if "S" input "Sale", if "S (partial)" input "Partial Sale", if "E" input "Exchange".
+Rule3: in Owner field: input "SP", "JT" or "DC" from the Owner column, or "Self" if it is empty.
+Rule4: in AssetType field: input the code in square brackets after the asset name, e.g. "ST", "OP", "GS", "CS".
+Rule5: in Description field: input the text after "D:" below the transaction, or "" if there is none.
+Rule6: for options (AssetType "OP"): input "Call" or "Put" in OptionType, the strike price as a number in Strike and the expiration date in Expiration. Otherwise leave them empty or 0.
+[
+	{
+		"Name": "input First Name and Last Name only",
+		"Owner": "input Owner",
+		"Asset": "input Full Asset Name",
+		"Ticker": "input Ticker for the Asset",
+		"Type": "input Transaction Type",
+		"Date": "input Date",
+		"Filed": "input Date under Notification Date",
+		"Amount": "input Amount",
+		"Cap":  True or False (boolean),
+		"AssetType": "input Asset Type code",
+		"Description": "input Description",
+		"OptionType": "input Call or Put",
+		"Strike": input Strike Price (number),
+		"Expiration": "input Expiration Date"
+	}
+]
+`
+
+// Result holds the trades extracted from one report, or why that failed.
+type Result struct {
+	Report clerk.Report
+	Trades []trade.Trade
+	Err    error
+}
+
+var verbose bool
+
+// concurrency limits how many reports are extracted at the same time.
+var concurrency = 3
+
+func SetVerbose(v bool) {
+	verbose = v
+}
+
+func SetConcurrency(n int) {
+	if n > 0 {
+		concurrency = n
+	}
+}
+
+// ProcessReports extracts every report on its own, at most concurrency at a
+// time, and returns one Result per report in the same order. A report that
+// fails does not affect the others.
+func ProcessReports(ex Extractor, fileContents [][]byte, reports []clerk.Report) []Result {
+	ctx := context.Background()
+
+	log.Printf("processing %d trade reports with %s...", len(reports), ex.Name())
+
+	results := make([]Result, len(reports))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, report := range reports {
+		wg.Add(1)
+		go func(i int, report clerk.Report) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			trades, err := ex.Extract(ctx, fileContents[i], report)
+			for j := range trades {
+				trades[j].ReportID = report.DocID
+				trades[j].URL = report.URL
+				trade.Normalize(&trades[j])
+				if !trades[j].Normalized() {
+					log.Printf("report %s: trade %d flagged: %s\n", report.URL, j+1, strings.Join(trades[j].ParseErrors, "; "))
+				}
+			}
+			results[i] = Result{Report: report, Trades: trades, Err: err}
+		}(i, report)
+	}
+	wg.Wait()
+
+	if verbose {
+		var count, failed int
+		for _, r := range results {
+			count += len(r.Trades)
+			if r.Err != nil {
+				failed++
+			}
+		}
+		log.Printf("%d trades in %d reports. %d reports failed.\n", count, len(reports), failed)
+	}
+
+	return results
+}
+
+// ParseTrades decodes the JSON answer of an LLM after checking it against
+// Schema. Besides a plain array it accepts markdown code fences and an
+// object wrapping the array, which chat models tend to produce.
+func ParseTrades(out string) ([]trade.Trade, error) {
+	out = strings.TrimSpace(out)
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no output data from model")
+	}
+	if strings.HasPrefix(out, "```") {
+		out = strings.TrimPrefix(out, "```json")
+		out = strings.TrimPrefix(out, "```")
+		out = strings.TrimSuffix(strings.TrimSpace(out), "```")
+		out = strings.TrimSpace(out)
+	}
+
+	if strings.HasPrefix(out, "{") {
+		var wrapped map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(out), &wrapped); err == nil {
+			for _, v := range wrapped {
+				if strings.HasPrefix(strings.TrimSpace(string(v)), "[") {
+					out = string(v)
+					break
+				}
+			}
+		}
+	}
+
+	if err := Validate([]byte(out)); err != nil {
+		return nil, err
+	}
+
+	var trades []trade.Trade
+	if err := json.Unmarshal([]byte(out), &trades); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON: %v, output: %s", err, out)
+	}
+	return trades, nil
+}
@@ -0,0 +1,88 @@
+package extract
+
+import (
+	"clerk_trades/clerk"
+	"clerk_trades/trade"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// concurrentExtractor fails the report with DocID fail and records how many
+// reports it extracted at the same time.
+type concurrentExtractor struct {
+	fail string
+
+	mu      sync.Mutex
+	running int
+	max     int
+}
+
+func (c *concurrentExtractor) Name() string { return "concurrent" }
+
+func (c *concurrentExtractor) Extract(ctx context.Context, pdf []byte, report clerk.Report) ([]trade.Trade, error) {
+	c.mu.Lock()
+	c.running++
+	c.max = max(c.max, c.running)
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.running--
+		c.mu.Unlock()
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	if report.DocID == c.fail {
+		return nil, errors.New("model failed")
+	}
+	return []trade.Trade{{Name: string(pdf), Ticker: "AAPL", Type: "Purchase"}}, nil
+}
+
+func (c *concurrentExtractor) Close() error { return nil }
+
+func TestProcessReports(t *testing.T) {
+	defer SetConcurrency(concurrency)
+	SetConcurrency(2)
+
+	var contents [][]byte
+	var reports []clerk.Report
+	for i := range 6 {
+		r := clerk.NewReport(fmt.Sprintf("https://example.com/public_disc/ptr-pdfs/2025/2000000%d.pdf", i))
+		contents = append(contents, []byte(r.DocID))
+		reports = append(reports, r)
+	}
+
+	ex := &concurrentExtractor{fail: "20000003"}
+	results := ProcessReports(ex, contents, reports)
+	if len(results) != len(reports) {
+		t.Fatalf("got %d results for %d reports", len(results), len(reports))
+	}
+	if ex.max > 2 {
+		t.Errorf("extracted %d reports at the same time, want at most 2", ex.max)
+	}
+
+	for i, res := range results {
+		r := reports[i]
+		if res.Report.DocID != r.DocID {
+			t.Errorf("result %d is of report %s, want %s", i, res.Report.DocID, r.DocID)
+			continue
+		}
+		if r.DocID == ex.fail {
+			if res.Err == nil || len(res.Trades) != 0 {
+				t.Errorf("failing report %s: %d trades, error %v", r.DocID, len(res.Trades), res.Err)
+			}
+			continue
+		}
+		if res.Err != nil || len(res.Trades) != 1 {
+			t.Errorf("report %s: %d trades, error %v", r.DocID, len(res.Trades), res.Err)
+			continue
+		}
+		// the trades are read from the report's own PDF
+		if tr := res.Trades[0]; tr.Name != r.DocID || tr.ReportID != r.DocID || tr.URL != r.URL {
+			t.Errorf("report %s: got trade of %s from report %s (%s)", r.DocID, tr.Name, tr.ReportID, tr.URL)
+		}
+	}
+}
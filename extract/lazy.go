@@ -0,0 +1,47 @@
+package extract
+
+import (
+	"clerk_trades/clerk"
+	"clerk_trades/trade"
+	"context"
+	"sync"
+)
+
+// Lazy creates its Extractor when the first report is handed to it. A
+// backend that cannot be created, e.g. without its API key, then only fails
+// the reports that need it instead of every report.
+type Lazy struct {
+	name   string
+	create func() (Extractor, error)
+
+	once sync.Once
+	ex   Extractor
+	err  error
+}
+
+// NewLazy returns an extractor named name that is created with create. The
+// name must be the one of the created extractor, as it keys the cache.
+func NewLazy(name string, create func() (Extractor, error)) *Lazy {
+	return &Lazy{name: name, create: create}
+}
+
+func (l *Lazy) Name() string {
+	return l.name
+}
+
+func (l *Lazy) Extract(ctx context.Context, pdf []byte, report clerk.Report) ([]trade.Trade, error) {
+	l.once.Do(func() {
+		l.ex, l.err = l.create()
+	})
+	if l.err != nil {
+		return nil, l.err
+	}
+	return l.ex.Extract(ctx, pdf, report)
+}
+
+func (l *Lazy) Close() error {
+	if l.ex == nil {
+		return nil
+	}
+	return l.ex.Close()
+}
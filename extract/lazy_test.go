@@ -0,0 +1,62 @@
+package extract
+
+import (
+	"clerk_trades/clerk"
+	"clerk_trades/trade"
+	"context"
+	"errors"
+	"testing"
+)
+
+type fakeExtractor struct {
+	closed bool
+}
+
+func (f *fakeExtractor) Name() string { return "fake" }
+
+func (f *fakeExtractor) Extract(context.Context, []byte, clerk.Report) ([]trade.Trade, error) {
+	return []trade.Trade{{Name: "A"}}, nil
+}
+
+func (f *fakeExtractor) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestLazy(t *testing.T) {
+	var created int
+	fake := &fakeExtractor{}
+	l := NewLazy("fake", func() (Extractor, error) {
+		created++
+		return fake, nil
+	})
+	if l.Name() != "fake" || created != 0 {
+		t.Fatalf("extractor created before the first report")
+	}
+	if err := l.Close(); err != nil || fake.closed {
+		t.Errorf("extractor that was never created was closed")
+	}
+
+	for range 2 {
+		if trades, err := l.Extract(context.Background(), nil, clerk.Report{}); err != nil || len(trades) != 1 {
+			t.Errorf("extracted %v, %v", trades, err)
+		}
+	}
+	if created != 1 {
+		t.Errorf("extractor created %d times, want 1", created)
+	}
+	if l.Close(); !fake.closed {
+		t.Error("extractor was not closed")
+	}
+}
+
+func TestLazyFails(t *testing.T) {
+	missing := errors.New("environment variable GEMINI_API_KEY not set")
+	l := NewLazy("fake", func() (Extractor, error) { return nil, missing })
+	if _, err := l.Extract(context.Background(), nil, clerk.Report{}); !errors.Is(err, missing) {
+		t.Errorf("got %v, want %v", err, missing)
+	}
+	if err := l.Close(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,93 @@
+package extract
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrSchema is returned when a model answer does not match Schema.
+var ErrSchema = errors.New("answer does not match trade schema")
+
+// Kind is the JSON type of a schema field.
+type Kind int
+
+const (
+	String Kind = iota
+	Boolean
+	Number
+)
+
+// Field describes one property of a trade in the model answer.
+type Field struct {
+	Name     string
+	Kind     Kind
+	Enum     []string
+	Required bool
+}
+
+// Schema is the shape every model answer must have: a JSON array of trades
+// with these fields. Backends that support structured output declare it to
+// the model, and every answer is checked against it.
+var Schema = []Field{
+	{Name: "Name", Kind: String, Required: true},
+	{Name: "Owner", Kind: String, Enum: []string{"Self", "SP", "JT", "DC"}, Required: true},
+	{Name: "Asset", Kind: String, Required: true},
+	{Name: "Ticker", Kind: String},
+	{Name: "Type", Kind: String, Enum: []string{"Purchase", "Sale", "Partial Sale", "Exchange"}, Required: true},
+	{Name: "Date", Kind: String, Required: true},
+	{Name: "Filed", Kind: String, Required: true},
+	{Name: "Amount", Kind: String, Required: true},
+	{Name: "Cap", Kind: Boolean, Required: true},
+	{Name: "AssetType", Kind: String},
+	{Name: "Description", Kind: String},
+	{Name: "OptionType", Kind: String, Enum: []string{"Call", "Put"}},
+	{Name: "Strike", Kind: Number},
+	{Name: "Expiration", Kind: String},
+}
+
+// Validate checks a JSON answer against Schema.
+func Validate(data []byte) error {
+	var items []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return fmt.Errorf("%w: not a JSON array of objects: %v", ErrSchema, err)
+	}
+
+	for i, item := range items {
+		for _, f := range Schema {
+			raw, ok := item[f.Name]
+			if !ok || string(raw) == "null" {
+				if f.Required {
+					return fmt.Errorf("%w: trade %d: missing %s", ErrSchema, i+1, f.Name)
+				}
+				continue
+			}
+
+			switch f.Kind {
+			case Boolean:
+				var b bool
+				if err := json.Unmarshal(raw, &b); err != nil {
+					return fmt.Errorf("%w: trade %d: %s must be a boolean", ErrSchema, i+1, f.Name)
+				}
+			case Number:
+				var n float64
+				if err := json.Unmarshal(raw, &n); err != nil {
+					return fmt.Errorf("%w: trade %d: %s must be a number", ErrSchema, i+1, f.Name)
+				}
+			case String:
+				var s string
+				if err := json.Unmarshal(raw, &s); err != nil {
+					return fmt.Errorf("%w: trade %d: %s must be a string", ErrSchema, i+1, f.Name)
+				}
+				if f.Required && s == "" {
+					return fmt.Errorf("%w: trade %d: %s is empty", ErrSchema, i+1, f.Name)
+				}
+				if len(f.Enum) > 0 && s != "" && !slices.Contains(f.Enum, s) {
+					return fmt.Errorf("%w: trade %d: %s %q is not one of %q", ErrSchema, i+1, f.Name, s, f.Enum)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package extract
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const valid = `{"Name": "Nancy Pelosi", "Owner": "SP", "Asset": "NVIDIA Corporation (NVDA) [OP]", "Ticker": "NVDA",
+"Type": "Purchase", "Date": "01/14/2025", "Filed": "01/17/2025", "Amount": "$1,000,001 - $5,000,000", "Cap": false,
+"AssetType": "OP", "Description": "Purchased 50 call options", "OptionType": "Call", "Strike": 80, "Expiration": "01/16/2026"}`
+
+func TestValidate(t *testing.T) {
+	// with sets a field of the valid trade to the JSON value, or drops it if value is ""
+	with := func(field, value string) string {
+		var trade map[string]any
+		if err := json.Unmarshal([]byte(valid), &trade); err != nil {
+			t.Fatal(err)
+		}
+		delete(trade, field)
+		if value != "" {
+			trade[field] = json.RawMessage(value)
+		}
+		data, err := json.Marshal([]any{trade})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	tests := []struct {
+		name   string
+		answer string
+		err    string // "" if valid
+	}{
+		{"valid", "[" + valid + "]", ""},
+		{"no trades", "[]", ""},
+		{"optional fields null", with("Ticker", "null"), ""},
+		{"optional field missing", with("Expiration", ""), ""},
+		{"empty optional enum", with("OptionType", `""`), ""},
+		{"not an array", valid, "not a JSON array"},
+		{"array of strings", `["Nancy Pelosi"]`, "not a JSON array"},
+		{"not JSON", "Here are the trades", "not a JSON array"},
+		{"required field missing", with("Owner", ""), "trade 1: missing Owner"},
+		{"required field null", with("Date", "null"), "trade 1: missing Date"},
+		{"required field empty", with("Asset", `""`), "trade 1: Asset is empty"},
+		{"string as boolean", with("Cap", `"false"`), "trade 1: Cap must be a boolean"},
+		{"string as number", with("Strike", `"80"`), "trade 1: Strike must be a number"},
+		{"number as string", with("Name", "1"), "trade 1: Name must be a string"},
+		{"value outside enum", with("Type", `"Buy"`), `trade 1: Type "Buy" is not one of`},
+		{"second trade", "[" + valid + `, {"Name": "Nancy Pelosi"}]`, "trade 2: missing Owner"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate([]byte(tt.answer))
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("valid answer returned %v:\n%s", err, tt.answer)
+				}
+				return
+			}
+			if !errors.Is(err, ErrSchema) || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("got %v, want %q:\n%s", err, tt.err, tt.answer)
+			}
+		})
+	}
+}
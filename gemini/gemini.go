@@ -1,106 +1,126 @@
 package gemini
 
 import (
-	"clerk_trades/utils"
+	"clerk_trades/clerk"
+	"clerk_trades/extract"
+	"clerk_trades/trade"
 	"context"
+	"errors"
 	"fmt"
 	"log"
-	"os"
 
 	"github.com/google/generative-ai-go/genai"
 	"google.golang.org/api/option"
 )
 
-type Trade struct {
-	Name   string `json:"Name"`
-	Asset  string `json:"Asset"`
-	Ticker string `json:"Ticker"`
-	Type   string `json:"Type"`
-	Date   string `json:"Date"`
-	Filed  string `json:"Filed"`
-	Amount string `json:"Amount"`
-	Cap    bool   `json:"Cap"`
-}
+const DefaultModel = "gemini-1.5-flash"
 
-var verbose bool
+// maxAsks is how often a report is sent before an answer that does not match
+// the schema fails its extraction.
+const maxAsks = 3
 
-func SetVerbose(v bool) {
-	verbose = v
+// Extractor extracts trades by sending the report PDF to Gemini.
+type Extractor struct {
+	client *genai.Client
+	model  *genai.GenerativeModel
+	name   string
 }
 
-func ProsessReports(fileContents [][]byte, links []string) ([]Trade, error) {
-	var Trades []Trade
-	ctx := context.Background()
-
-	apiKey := os.Getenv("GEMINI_API_KEY")
+// New creates a Gemini extractor. An empty model uses DefaultModel.
+func New(apiKey, model string) (*Extractor, error) {
 	if apiKey == "" {
-		log.Fatalln("error: environment variable GEMINI_API_KEY not set")
+		return nil, fmt.Errorf("environment variable GEMINI_API_KEY not set")
+	}
+	if model == "" {
+		model = DefaultModel
 	}
 
-	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
+	client, err := genai.NewClient(context.Background(), option.WithAPIKey(apiKey))
 	if err != nil {
 		return nil, fmt.Errorf("failed to creating client: %v", err)
 	}
-	defer client.Close()
-
-	model := client.GenerativeModel("gemini-1.5-flash")
-	//model := client.GenerativeModel("gemini-2.0-flash-exp")
-	model.SetTemperature(0)
-	model.SetTopP(0)
-	model.SetTopK(0)
-	model.ResponseMIMEType = "application/json"
-	model.SystemInstruction = genai.NewUserContent(genai.Text(`
-It should read data from the PDF file and write data into the JSON array described below with some rules.
-Rule1: Name can be obtained under Filer Information. Input First Name and Last Name only! Dont include "Hon.", "Mrs", "Mr", etc.
-Rule2: in Type field (Transaction Type): if "P" input "Purchase", if "S" input "Sale".
-[
-	{
-		"Name": "input First Name and Last Name only",
-		"Asset": "input Full Asset Name",
-		"Ticker": "input Ticker for the Asset",
-		"Type": "input Transaction Type",
-		"Date": "input Date",
-		"Filed": "input Date under Notification Date",
-		"Amount": "input Amount",
-		"Cap":  True or False (boolean)
-	}
-]
-`))
 
-	var parts []genai.Part
-	parts = append(parts, genai.Text("create JSON with the very important instructions"))
-	for _, data := range fileContents {
-		parts = append(parts, genai.Blob{
+	m := client.GenerativeModel(model)
+	m.SetTemperature(0)
+	m.SetTopP(0)
+	m.SetTopK(0)
+	m.ResponseMIMEType = "application/json"
+	m.ResponseSchema = responseSchema()
+	m.SystemInstruction = genai.NewUserContent(genai.Text(extract.Prompt))
+
+	return &Extractor{client: client, model: m, name: "gemini/" + model}, nil
+}
+
+func (e *Extractor) Name() string {
+	return e.name
+}
+
+func (e *Extractor) Close() error {
+	return e.client.Close()
+}
+
+func (e *Extractor) Extract(ctx context.Context, content []byte, report clerk.Report) ([]trade.Trade, error) {
+	cs := e.model.StartChat()
+	parts := []genai.Part{
+		genai.Text("create JSON with the very important instructions"),
+		genai.Blob{
 			MIMEType: "application/pdf",
-			Data:     data,
-		})
+			Data:     content,
+		},
 	}
 
-	log.Printf("processing trade report...")
+	// re-ask within the same chat when the answer violates the schema
+	var err error
+	for ask := 1; ask <= maxAsks; ask++ {
+		var resp *genai.GenerateContentResponse
+		resp, err = cs.SendMessage(ctx, parts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate content: %v", err)
+		}
 
-	resp, err := model.GenerateContent(ctx, parts...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate content: %v", err)
+		var trades []trade.Trade
+		trades, err = extract.ParseTrades(getResponse(resp))
+		if err == nil {
+			return trades, nil
+		}
+		if !errors.Is(err, extract.ErrSchema) {
+			return nil, err
+		}
+
+		log.Printf("report %s: %v. asking again (%d/%d).\n", report.URL, err, ask, maxAsks)
+		parts = []genai.Part{genai.Text(fmt.Sprintf("Your answer is invalid: %v. Answer again with the corrected JSON array only.", err))}
 	}
+	return nil, err
+}
 
-	out := getResponse(resp)
-	if len(out) == 0 {
-		return nil, fmt.Errorf("no output data from gemini")
+// responseSchema declares extract.Schema to Gemini.
+func responseSchema() *genai.Schema {
+	item := &genai.Schema{
+		Type:       genai.TypeObject,
+		Properties: map[string]*genai.Schema{},
 	}
-	if err := utils.SafeUnmarshal(out, &Trades); err != nil {
-		log.Fatalf("safe unmarshal failed: %v", err)
+	for _, f := range extract.Schema {
+		prop := &genai.Schema{Type: genai.TypeString}
+		switch f.Kind {
+		case extract.Boolean:
+			prop.Type = genai.TypeBoolean
+		case extract.Number:
+			prop.Type = genai.TypeNumber
+		}
+		if len(f.Enum) > 0 {
+			prop.Format = "enum"
+			prop.Enum = f.Enum
+		}
+		item.Properties[f.Name] = prop
+		if f.Required {
+			item.Required = append(item.Required, f.Name)
+		}
 	}
 
-	// print trades
-	strTrades := PrintTrades(Trades)
-	log.Print("\r\n", strTrades)
-
-	// Trades = checkTrades(Trades)
-	if verbose {
-		log.Printf("%d trades in %d reports.\n", len(Trades), len(links))
+	return &genai.Schema{
+		Type:  genai.TypeArray,
+		Items: item,
 	}
-
-	return Trades, nil
 }
 
 func getResponse(resp *genai.GenerateContentResponse) string {
@@ -114,71 +134,3 @@ func getResponse(resp *genai.GenerateContentResponse) string {
 	}
 	return str
 }
-
-func PrintTrades(trades []Trade) string {
-	output := "\n"
-	for _, trade := range trades {
-		output += fmt.Sprintf("Name:    %-20s\n", trade.Name)
-		output += fmt.Sprintf("Asset:   %-20s\n", trade.Asset)
-		output += fmt.Sprintf("Ticker:  %-20s\n", trade.Ticker)
-		output += fmt.Sprintf("Type:    %-20s\n", trade.Type)
-		output += fmt.Sprintf("Date:    %-20s\n", trade.Date)
-		output += fmt.Sprintf("Filed:   %-20s\n", trade.Filed)
-		output += fmt.Sprintf("Amount:  %-20s\n", trade.Amount)
-		output += fmt.Sprintf("Cap:     %-20v\n\n", trade.Cap)
-	}
-	return output
-}
-
-// func checkTrades(Trades []Trade) []Trade {
-// 	var count int
-// 	var trades []Trade
-
-// 	for _, newTrade := range Trades {
-// 		// empty fileds are not accepted
-// 		if newTrade.Ticker == "" {
-// 			count++
-// 			continue
-// 		}
-// 		if newTrade.Type == "" {
-// 			count++
-// 			continue
-// 		}
-// 		if newTrade.Date == "" {
-// 			count++
-// 			continue
-// 		}
-// 		if newTrade.Filed == "" {
-// 			count++
-// 			continue
-// 		}
-// 		trades = append(trades, newTrade)
-// 	}
-
-// 	if count == 0 {
-// 		return Trades
-// 	}
-
-// 	if verbose {
-// 		log.Printf("removed 3 trades has bad gemini data.\n")
-// 	}
-
-// 	return trades
-// }
-
-// func hasMatchingWord(new, old string) bool {
-// 	if new == "" || old == "" {
-// 		return false
-// 	}
-// 	newWords := strings.Fields(new)
-// 	existingWords := strings.Fields(old)
-
-// 	for _, newWord := range newWords {
-// 		for _, existingWord := range existingWords {
-// 			if newWord == existingWord {
-// 				return true
-// 			}
-// 		}
-// 	}
-// 	return false
-// }
@@ -0,0 +1,47 @@
+package gemini
+
+import (
+	"clerk_trades/extract"
+	"slices"
+	"testing"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+func TestResponseSchema(t *testing.T) {
+	s := responseSchema()
+	if s.Type != genai.TypeArray || s.Items == nil || s.Items.Type != genai.TypeObject {
+		t.Fatalf("schema is not an array of objects: %+v", s)
+	}
+	item := s.Items
+
+	var required []string
+	for _, f := range extract.Schema {
+		if f.Required {
+			required = append(required, f.Name)
+		}
+
+		prop, ok := item.Properties[f.Name]
+		if !ok {
+			t.Errorf("%s is missing", f.Name)
+			continue
+		}
+		want := map[extract.Kind]genai.Type{
+			extract.String:  genai.TypeString,
+			extract.Boolean: genai.TypeBoolean,
+			extract.Number:  genai.TypeNumber,
+		}[f.Kind]
+		if prop.Type != want {
+			t.Errorf("%s is of type %v, want %v", f.Name, prop.Type, want)
+		}
+		if !slices.Equal(prop.Enum, f.Enum) || (prop.Format == "enum") != (len(f.Enum) > 0) {
+			t.Errorf("%s has enum %q (format %q), want %q", f.Name, prop.Enum, prop.Format, f.Enum)
+		}
+	}
+	if len(item.Properties) != len(extract.Schema) {
+		t.Errorf("%d properties, want %d", len(item.Properties), len(extract.Schema))
+	}
+	if !slices.Equal(item.Required, required) {
+		t.Errorf("required %q, want %q", item.Required, required)
+	}
+}
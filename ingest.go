@@ -0,0 +1,146 @@
+package main
+
+import (
+	"clerk_trades/archive"
+	"clerk_trades/clerk"
+	"clerk_trades/store"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Clerk DocIDs are numeric; local files named like one keep it
+var docIDRe = regexp.MustCompile(`^\d{8}$`)
+
+// ingestFiles runs local PTR PDFs through the same pipeline as scraped
+// reports. pattern is a file, a glob or a directory, which is walked for
+// PDF files. A file of a report that is stored already, e.g. one discovered
+// on the Clerk site, processes that report and never replaces its archived PDF.
+// Reports no watchlist wants are skipped like queued ones.
+func ingestFiles(pattern string) error {
+	files, err := findPDFs(pattern)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no PDF files found in %s", pattern)
+	}
+	log.Printf("ingesting %d local reports.\n", len(files))
+
+	var reports []clerk.Report
+	docIDs := make(map[string]bool)
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			log.Printf("failed to read %s: %v\n", file, err)
+			continue
+		}
+
+		r := localReport(file, content)
+		if docIDs[r.DocID] {
+			log.Printf("skipping %s, report %s was ingested from another file.\n", file, r.DocID)
+			continue
+		}
+		docIDs[r.DocID] = true
+		if e, ok := db.Report(r.DocID); ok {
+			if verbose {
+				log.Printf("%s is report %s, which is stored already.\n", file, r.DocID)
+			}
+			r = e.Report
+		}
+		if _, ok := pdfs.Get(r); !ok {
+			if err := pdfs.Put(r, content); err != nil {
+				log.Printf("failed to archive %s: %v\n", file, err)
+				continue
+			}
+		}
+		reports = append(reports, r)
+	}
+
+	if _, err := db.Enqueue(reports...); err != nil {
+		return err
+	}
+	if err := skipUnwanted(); err != nil {
+		return err
+	}
+
+	var entries []store.Entry
+	for _, r := range reports {
+		if e, ok := db.Entry(r); ok && e.State != store.Notified && e.State != store.Skipped {
+			entries = append(entries, e)
+		}
+	}
+	if len(entries) == 0 {
+		log.Println("nothing new to process.")
+		return nil
+	}
+
+	for start := 0; start < len(entries); start += cfg.Schedule.Batch {
+		end := min(start+cfg.Schedule.Batch, len(entries))
+		if err := processReports(entries[start:end]); err != nil {
+			return err
+		}
+		log.Printf("processed %d/%d local reports.\n", end, len(entries))
+	}
+	return nil
+}
+
+// findPDFs expands pattern to the PDF files it names.
+func findPDFs(pattern string) ([]string, error) {
+	if info, err := os.Stat(pattern); err == nil && info.IsDir() {
+		var files []string
+		err := filepath.WalkDir(pattern, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.EqualFold(filepath.Ext(path), ".pdf") {
+				files = append(files, path)
+			}
+			return nil
+		})
+		return files, err
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+	}
+	var files []string
+	for _, m := range matches {
+		if info, err := os.Stat(m); err == nil && !info.IsDir() {
+			files = append(files, m)
+		}
+	}
+	return files, nil
+}
+
+// localReport describes a local PDF. Files named after a Clerk DocID keep
+// it, other files are identified by their content hash. The year is taken
+// from the parent directory if it is named like one.
+func localReport(file string, content []byte) clerk.Report {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		abs = file
+	}
+
+	r := clerk.Report{
+		URL:    "file://" + filepath.ToSlash(abs),
+		Source: clerk.SourceLocal,
+	}
+
+	base := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	if docIDRe.MatchString(base) {
+		r.DocID = base
+	} else {
+		r.DocID = "local-" + archive.Hash(content)[:12]
+	}
+	if year, err := strconv.Atoi(filepath.Base(filepath.Dir(abs))); err == nil && year > 2000 {
+		r.Year = year
+	}
+	return r
+}
@@ -1,338 +1,340 @@
 package main
 
 import (
+	"clerk_trades/archive"
 	"clerk_trades/clerk"
+	"clerk_trades/config"
+	"clerk_trades/download"
 	"clerk_trades/email"
-	"clerk_trades/gemini"
-	"clerk_trades/utils"
-	"context"
+	"clerk_trades/extract"
+	"clerk_trades/ptr"
+	"clerk_trades/roster"
+	"clerk_trades/security"
+	"clerk_trades/store"
+	"clerk_trades/trade"
+	"clerk_trades/watchlist"
+	"flag"
 	"fmt"
 	"io"
 	"log"
-	"net/http"
 	"os"
-	"os/signal"
+	"path/filepath"
 	"strconv"
 	"strings"
-	"sync"
-	"syscall"
 	"time"
 )
 
 func usage(code int) {
 	fmt.Printf(`CLERK TRADES - U.S. Government Official Financial Report Tracker
-Usage: %s [<ticker_duration> | <list>] [OPTIONS]
-
-Arguments:
-  ticker_duration     Duration for the application ticker to check for new
-                      reports on Clerk website. Minimum 3h (e.g. 24h, 72h).
-                      Only accepts 'h' for hours before the integer.
-                      If not specified, it will not check for new reports.
-  list                Specify the number of reports to list their trades.
-                      (type=int). This argument must be betweengreater than
-                      0 but less that 6.
-                      If used, the program will exit after printing.
-
-Note: Only one of these two arguments may be provided at a time.
-
-OPTIONS:
-  -n, --name <name>   List reports of a specific individual.
-  -e, --email         Enable email notifications for trade results via Mailgun. 
-                      Configure settings in 'gunmail.config' to activate.
-  --log               Save logs to file.
-  -v, --verbose       Enable verbose output for detailed logging and information.
-  -h, --help          Display this help menu.
+Usage: %s <command> [OPTIONS] [ARGUMENTS]
+
+Commands:
+`, os.Args[0])
+	for _, c := range commands {
+		fmt.Printf("  %-11s %s\n", c.name, c.summary)
+	}
+	fmt.Printf(`  help        Display this help menu, or the help of a command.
+
+Run '%s help <command>' for the arguments and options of a command.
 `, os.Args[0])
 	os.Exit(code)
 }
 
 var (
 	verbose bool
-	mail    bool
-	name    string
-)
 
-func main() {
-	var update time.Duration
-	var listReports int
+	// cfg holds the settings of the config file, overridden by the
+	// environment and the options of the command.
+	cfg        = config.Default()
+	configFile = config.File()
 
-	for i := 1; i < len(os.Args); i++ {
-		arg := os.Args[i]
+	// source discovers new reports. setup builds the configured source
+	// unless another clerk.ReportSource, e.g. a fake one that needs no
+	// browser, was set before.
+	source clerk.ReportSource
 
-		switch {
-		case arg == "-h" || arg == "--help" || arg == "help":
-			usage(0)
+	// db holds discovered reports until they are processed, and the
+	// trades extracted from them.
+	db      *store.Store
+	noCache bool
+	rules   = trade.DefaultRules()
 
-		case arg == "-v" || arg == "--verbose" || arg == "verbose":
-			verbose = true
+	// members resolves member names to canonical member IDs.
+	members *roster.Roster
 
-		case arg == "--log":
-			logName := time.Now().Format("01021504") + ".log"
-			logFile, err := os.Create(logName)
-			if err != nil {
-				log.Fatalf("could not create logfile %q: %v", logFile.Name(), err)
-			}
-			log.SetOutput(io.MultiWriter(os.Stderr, logFile))
-			log.Printf("successfully created logfile %q.\n", logFile.Name())
-
-		case arg == "-e" || arg == "--email" || arg == "email":
-			log.Printf("loading Mailgun settings..")
-			err := email.LoadMailGun()
-			if err != nil {
-				log.Fatalln(err)
-			}
-			mail = true
-			log.Printf("results will be sent to %v\n", email.Mailgun.EmailTo)
-
-		case strings.HasPrefix(arg, "-n") || strings.HasPrefix(arg, "--name"):
-			if strings.Contains(arg, "=") {
-				name = strings.SplitN(arg, "=", 2)[1]
-			} else if i+1 < len(os.Args) && !strings.HasPrefix(os.Args[i+1], "-") {
-				name = os.Args[i+1]
-				i++
-			} else {
-				log.Fatalln("error: -n flag requires a name.")
-			}
-
-		default:
-			if n, err := strconv.Atoi(arg); err == nil && n > 0 && n < 6 {
-				listReports = n
-			} else if duration, err := parseCustomDuration(arg); err == nil {
-				if duration < 3*time.Hour {
-					log.Fatalln("error: minimum duration must be 3h.")
-				}
-				update = duration
-			} else {
-				if name != "" {
-					continue
-				}
-				log.Fatalln("error: invalid argument:", arg)
-			}
-		}
-	}
+	// securities resolves asset descriptions to tickers.
+	securities *security.Master
 
-	if name != "" {
-		update = time.Duration(24 * time.Hour)
-		listReports = 0
+	// pdfs keeps every downloaded report PDF.
+	pdfs       *archive.Archive
+	downloader = download.New(4)
 
-		if err := copyFile(clerk.FILE_LINKS, clerk.FILE_BACKUP); err != nil {
-			log.Println("error copying file:", err)
-		}
-		if err := os.Remove(clerk.FILE_LINKS); err != nil {
-			log.Println("error removing file:", err)
-		}
-		go HandleInterrupt()
-	}
+	logToFile bool
+)
 
-	if update == 0 && (listReports > 5 || listReports <= 0) || update != 0 && listReports != 0 {
+func main() {
+	if len(os.Args) < 2 {
 		usage(1)
 	}
 
-	if verbose {
-		log.Println("verbose is active.")
-		gemini.SetVerbose(true)
-		clerk.SetVerbose(true)
-	}
-
-	if update != 0 {
-		log.Printf("ticker scheduled to check for new reports every %s.\n", fmt.Sprintf("%.0fh", update.Hours()))
-
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel()
-
-		ticker := time.NewTicker(update)
-		defer ticker.Stop()
-
-		go func() {
-			for {
-				select {
-				case <-ctx.Done():
-					if verbose {
-						log.Println("ticker stopped.")
-					}
-					return
-				case <-ticker.C:
-					if err := checkReports(update, listReports, name); err != nil {
-						log.Println("error:", err)
-					}
-				}
+	cmd, args := os.Args[1], os.Args[2:]
+	if cmd == "help" || cmd == "-h" || cmd == "--help" {
+		if len(args) > 0 {
+			if c := findCommand(args[0]); c != nil {
+				c.run([]string{"-h"})
 			}
-		}()
+		}
+		usage(0)
 	}
 
-	if err := checkReports(update, listReports, name); err != nil {
-		log.Println("error:", err)
+	c := findCommand(cmd)
+	if c == nil {
+		fmt.Printf("unknown command %q.\n\n", cmd)
+		usage(2)
 	}
 
-	if update == 0 {
-		if verbose {
-			log.Println("ticker is disabled. program exit.")
-		}
-		return
+	// the config is loaded before the options are parsed, so they override it
+	var err error
+	file, required := configArg(args)
+	if cfg, err = config.Load(file, required); err != nil {
+		log.Fatalln("error:", err)
 	}
+	configFile = file
 
-	select {}
+	if err := c.run(args); err != nil {
+		log.Fatalln("error:", err)
+	}
 }
 
-func checkReports(update time.Duration, listReports int, name string) error {
-	var err error
-	var files []string
-	var links []string
-	var loop bool
-
-	links, _ = utils.ReadJSON[[]string](clerk.FILE_LINKS)
-	if verbose {
-		log.Printf("loaded %d reports.\n", len(links))
+// newFlagSet creates the flag set of a command with the options every
+// command accepts. args and help describe the command in its usage.
+func newFlagSet(name, args, help string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s [OPTIONS] %s\n\n%s\nOPTIONS:\n", os.Args[0], name, args, help)
+		fs.PrintDefaults()
 	}
+	fs.BoolVar(&verbose, "v", false, "Shorthand for -verbose.")
+	fs.BoolVar(&verbose, "verbose", false, "Enable verbose output for detailed logging and information.")
+	fs.BoolVar(&logToFile, "log", false, "Save logs to file.")
+	fs.String("config", configFile, "Config file. Can also be set with "+config.ENV_CONFIG+".")
+	fs.StringVar(&cfg.Storage.Path, "storage", cfg.Storage.Path, "Directory of the store, the report links, the archive and the cache.")
+	return fs
+}
 
-	if update != 0 {
-		if name != "" {
-			log.Printf("checking for new reports of %s.\n", name)
-		} else {
-			log.Println("checking for new reports.")
+// configArg returns the config file given with -config in args, or the
+// default one. The file is required if it was named explicitly.
+func configArg(args []string) (string, bool) {
+	for i, arg := range args {
+		if arg == "--" {
+			break
 		}
-		files, err, loop = clerk.SiteCheck(links, name)
-		if err != nil {
-			return err
+		if !strings.HasPrefix(arg, "-") {
+			continue
 		}
-	} else {
-		if len(links) == 0 {
-			log.Fatalln("no report links stored. run program with updater first, to links from clerk site.")
+		arg = strings.TrimLeft(arg, "-")
+		if arg == "config" && i+1 < len(args) {
+			return args[i+1], true
 		}
-		files = links
-		if listReports > 0 {
-			if len(files)-1 >= listReports {
-				files = files[len(files)-listReports:] // Keep only the last listReports files
-			}
+		if file, ok := strings.CutPrefix(arg, "config="); ok {
+			return file, true
 		}
 	}
+	return config.File(), os.Getenv(config.ENV_CONFIG) != ""
+}
 
-	if len(files) > 0 {
-		log.Printf("allocating space for %d reports in memory.\n", len(files))
-	}
-
-	var fileContent [][]byte
-	var wg sync.WaitGroup
-	var mu sync.Mutex
-	for _, file := range files {
+// sourceFlags adds the options of the commands that discover reports.
+func sourceFlags(fs *flag.FlagSet) {
+	fs.BoolFunc("index", "Discover reports from the yearly FD index archive over plain\nHTTP instead of the browser.", func(value string) error {
+		index, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		cfg.Sources.Discovery = "browser"
+		if index {
+			cfg.Sources.Discovery = "index"
+		}
+		return nil
+	})
+	fs.StringVar(&cfg.Sources.URL, "url", cfg.Sources.URL, "Base URL of the Clerk site.")
+}
 
-		wg.Add(1)
-		go func(file string) {
-			defer wg.Done()
+// watchlistFlags adds the options of the commands that notify watchlists.
+func watchlistFlags(fs *flag.FlagSet) {
+	member := func(name string) error {
+		cfg.Filters.Watchlists = append(cfg.Filters.Watchlists, watchlist.Watchlist{
+			Name:    name,
+			Members: []string{name},
+		})
+		return nil
+	}
+	fs.Func("n", "Shorthand for -name.", member)
+	fs.Func("name", "Only notify trades of a specific individual, in a watchlist of its\nown. Can be repeated. Watchlists of the config file are kept.", member)
+}
 
-			content, err := fetchFileContent(file)
-			if err != nil {
-				log.Printf("failed to fetch content for report %s: %v\n", file, err)
-				return
-			}
+// pipelineFlags adds the options of the commands that download, extract and
+// notify reports.
+func pipelineFlags(fs *flag.FlagSet) {
+	fs.IntVar(&cfg.Schedule.Batch, "b", cfg.Schedule.Batch, "Shorthand for -batch.")
+	fs.IntVar(&cfg.Schedule.Batch, "batch", cfg.Schedule.Batch, "Number of queued reports processed at a time.")
+	fs.IntVar(&cfg.Extractor.Concurrency, "c", cfg.Extractor.Concurrency, "Shorthand for -concurrency.")
+	fs.IntVar(&cfg.Extractor.Concurrency, "concurrency", cfg.Extractor.Concurrency, "Number of reports extracted at the same time. Every report is\nextracted on its own.")
+	fs.IntVar(&cfg.Sources.Workers, "w", cfg.Sources.Workers, "Shorthand for -workers.")
+	fs.IntVar(&cfg.Sources.Workers, "workers", cfg.Sources.Workers, "Number of reports downloaded at the same time. Failed\ndownloads are retried with an increasing delay.")
+	fs.StringVar(&cfg.Extractor.Backend, "x", cfg.Extractor.Backend, "Shorthand for -extractor.")
+	fs.StringVar(&cfg.Extractor.Backend, "extractor", cfg.Extractor.Backend, `Backend that reads reports whose text layer cannot be parsed,
+like scanned filings:
+  gemini   Google Gemini. Needs GEMINI_API_KEY for the reports it
+           reads, the model can be set with GEMINI_MODEL.
+  openai   Any OpenAI-compatible chat endpoint, e.g. a local Ollama
+           or llama.cpp server. Configure with OPENAI_BASE_URL,
+           OPENAI_MODEL and OPENAI_API_KEY. Only the text layer is
+           sent, so scanned filings without one still fail.
+  ptr      No backend. Only parse the text layer.`)
+	fs.BoolVar(&cfg.Notifiers.Email.Enabled, "e", cfg.Notifiers.Email.Enabled, "Shorthand for -email.")
+	fs.BoolVar(&cfg.Notifiers.Email.Enabled, "email", cfg.Notifiers.Email.Enabled, "Enable email notifications for trade results via Mailgun.\nConfigure them in notifiers.email of the config file.")
+}
 
-			mu.Lock()
-			fileContent = append(fileContent, content)
-			mu.Unlock()
-		}(file)
+// parseArgs parses the options of a command, which may be given before,
+// between or after its arguments, and returns the arguments. Everything
+// after "--" is an argument. It exits with the usage of the command if
+// their number is not between min and max.
+func parseArgs(fs *flag.FlagSet, args []string, min, max int) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		if parsed := len(args) - len(fs.Args()); parsed > 0 && args[parsed-1] == "--" {
+			positional = append(positional, fs.Args()...)
+			break
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
 	}
 
-	wg.Wait()
-	if len(fileContent) == 0 {
-		log.Println("nothing new to process.")
-		return err
+	if len(positional) < min || len(positional) > max {
+		if len(positional) < min {
+			fmt.Fprintf(fs.Output(), "error: %s requires more arguments.\n\n", fs.Name())
+		} else {
+			fmt.Fprintf(fs.Output(), "error: too many arguments: %s\n\n", strings.Join(positional[max:], " "))
+		}
+		fs.Usage()
+		os.Exit(2)
 	}
+	return positional
+}
 
-	// Process reports
-	trades, err := gemini.ProsessReports(fileContent, files)
-	if err != nil {
+// setup validates the settings and opens the store, the archive, the
+// validation rules, the security master and the member roster.
+func setup() error {
+	if err := openLog(); err != nil {
 		return err
 	}
 
-	if mail {
-		emailBody, err := email.GenerateEmailBody(trades)
-		if err != nil {
+	if cfg.Notifiers.Email.Enabled {
+		if err := cfg.MigrateMailGun(); err != nil {
 			return err
 		}
-		if email.Mailgun.Paid {
-			if err := email.SendHTMLToMailingList(emailBody); err != nil {
-				return err
-			}
-		} else {
-			if err := email.SendHTMLTo(emailBody); err != nil {
-				return err
-			}
-		}
-		if verbose {
-			log.Println("trade reports have been e-mailed.")
-		}
 	}
-
-	if loop {
-		defer func() {
-			go checkReports(update, listReports, name)
-		}()
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid settings:\n%v", err)
 	}
-
-	return nil
-}
-
-func fetchFileContent(link string) ([]byte, error) {
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", link, nil)
-	if err != nil {
-		return nil, err
+	extract.SetConcurrency(cfg.Extractor.Concurrency)
+	downloader.Workers = cfg.Sources.Workers
+
+	switch {
+	case source != nil:
+	case cfg.Sources.Discovery == "index":
+		source = clerk.NewIndexSource(cfg.Sources.URL)
+	default:
+		source = clerk.NewBrowserSource(cfg.Sources.URL)
 	}
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/117.0.5938.62 Safari/537.36")
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
+	if verbose {
+		log.Println("verbose is active.")
+		extract.SetVerbose(true)
+		ptr.SetVerbose(true)
+		download.SetVerbose(true)
+		clerk.SetVerbose(true)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch file: %s", resp.Status)
+	if e := cfg.Notifiers.Email; e.Enabled {
+		log.Printf("loading Mailgun settings..")
+		err := email.Setup(&email.MailGun{
+			APIKey:  e.APIKey,
+			Domain:  e.Domain,
+			EmailTo: email.ValidEmails(e.To),
+			Paid:    e.Paid,
+		})
+		if err != nil {
+			return err
+		}
+		log.Printf("results will be sent to %v\n", email.Mailgun.EmailTo)
 	}
 
-	return io.ReadAll(resp.Body)
-}
-
-func parseCustomDuration(input string) (time.Duration, error) {
-	if strings.HasSuffix(input, "h") {
-		hours := strings.TrimSuffix(input, "h")
-		if n, err := strconv.Atoi(hours); err == nil {
-			return time.Duration(n) * time.Hour, nil
-		}
+	if err := os.MkdirAll(cfg.Storage.Path, 0755); err != nil {
+		return fmt.Errorf("failed to create storage directory: %v", err)
+	}
+	clerk.SetDir(cfg.Storage.Path)
+	if err := clerk.RecoverBackup(); err != nil {
+		return err
 	}
-	return 0, fmt.Errorf("invalid duration format; only hours (h) are accepted")
-}
 
-func HandleInterrupt() {
-	closeChan := make(chan os.Signal, 1)
-	signal.Notify(closeChan, os.Interrupt, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	var err error
+	file := storagePath(roster.FILE_ROSTER)
+	if members, err = roster.Load(file); err != nil {
+		return err
+	}
+	if _, err := os.Stat(file); err != nil {
+		log.Printf("no %s, only the %d members of the bundled roster are known. import the current members with 'clerk_trades members import <file>'.\n",
+			file, len(members.Members()))
+	}
 
-	s := <-closeChan
-	log.Printf("\n%+v recived. shutting down.\n", s)
+	if db, err = store.Open(storagePath(store.FILE_STORE)); err != nil {
+		return err
+	}
+	if pdfs, err = archive.Open(storagePath(archive.DIR_ARCHIVE), db.Manifest()); err != nil {
+		return err
+	}
+	if rules, err = trade.LoadRules(storagePath(trade.FILE_RULES)); err != nil {
+		return err
+	}
 
-	if err := copyFile(clerk.FILE_BACKUP, clerk.FILE_LINKS); err != nil {
-		log.Println("error copying file:", err)
+	if securities, err = security.Load(cfg.Storage.Path); err != nil {
+		return err
 	}
-	if err := os.Remove(clerk.FILE_BACKUP); err != nil {
-		log.Println("error removing file:", err)
+	if verbose && securities.Len() > 0 {
+		log.Printf("loaded %d securities.\n", securities.Len())
 	}
 
-	os.Exit(0)
+	db.SetResolver(members.ID)
+	for i := range cfg.Filters.Watchlists {
+		cfg.Filters.Watchlists[i].Resolve(func(name string) string {
+			return members.ID(name, "")
+		})
+	}
+	return startLists()
 }
 
-func copyFile(src, dst string) error {
-	sourceFile, err := os.Open(src)
-	if err != nil {
-		return err
+// openLog copies the log to a new log file if requested.
+func openLog() error {
+	if !logToFile {
+		return nil
 	}
-	defer sourceFile.Close()
-
-	destFile, err := os.Create(dst)
+	logName := time.Now().Format("01021504") + ".log"
+	logFile, err := os.Create(logName)
 	if err != nil {
-		return err
+		return fmt.Errorf("could not create logfile %q: %v", logName, err)
 	}
-	defer destFile.Close()
+	log.SetOutput(io.MultiWriter(os.Stderr, logFile))
+	log.Printf("successfully created logfile %q.\n", logFile.Name())
+	return nil
+}
 
-	_, err = io.Copy(destFile, sourceFile)
-	return err
+// storagePath returns the path of file in the storage directory.
+func storagePath(file string) string {
+	return filepath.Join(cfg.Storage.Path, file)
 }
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"clerk_trades/clerk"
+	"clerk_trades/config"
+	"clerk_trades/store"
+	"clerk_trades/trade"
+	"clerk_trades/utils"
+	"clerk_trades/watchlist"
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSource discovers fixed reports without a browser.
+type fakeSource struct {
+	reports []clerk.Report
+}
+
+func (s *fakeSource) Discover(ctx context.Context, q clerk.Query, fn clerk.PageFunc) error {
+	fn(s.reports)
+	return nil
+}
+
+// setupTest sets up the program with a fresh storage directory and src as
+// report source.
+func setupTest(t *testing.T, src clerk.ReportSource) {
+	t.Helper()
+	cfg = config.Default()
+	cfg.Storage.Path = t.TempDir()
+	cfg.Extractor.Backend = "ptr"
+	source = src
+	t.Cleanup(func() { source = nil })
+
+	if err := importRoster([]string{"roster/testdata/legislators-current.json"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := setup(); err != nil {
+		t.Fatal(err)
+	}
+	if source != src {
+		t.Fatal("setup replaced the report source")
+	}
+}
+
+func TestCheckReportsWithFakeSource(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	a := clerk.NewReport(srv.URL + "/public_disc/ptr-pdfs/2025/20000001.pdf")
+	a.Name = "Pelosi, Nancy"
+	a.Office = "CA11"
+	b := clerk.NewReport(srv.URL + "/public_disc/ptr-pdfs/2025/20000002.pdf")
+	b.Name = "Doe, John"
+	setupTest(t, &fakeSource{reports: []clerk.Report{a, b}})
+
+	if err := checkReports(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := db.Entries(store.EntryFilter{})
+	if len(entries) != 2 {
+		t.Fatalf("store has %d reports, want 2", len(entries))
+	}
+	for _, e := range entries {
+		if e.Attempts != 1 || e.LastError == "" {
+			t.Errorf("report %s: attempts %d, error %q; want a failed download", e.DocID, e.Attempts, e.LastError)
+		}
+	}
+
+	links, err := utils.ReadJSON[[]clerk.Report](clerk.Path(clerk.FILE_LINKS))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 2 {
+		t.Errorf("%s has %d reports, want 2", clerk.FILE_LINKS, len(links))
+	}
+
+	// known reports are not queued again
+	if err := checkReports(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(db.Entries(store.EntryFilter{})); n != 2 {
+		t.Errorf("store has %d reports after the second check, want 2", n)
+	}
+}
+
+func TestCheckReportsInterrupted(t *testing.T) {
+	a := clerk.NewReport("https://example.com/public_disc/ptr-pdfs/2025/20000001.pdf")
+	setupTest(t, &fakeSource{reports: []clerk.Report{a}})
+
+	// reports are still discovered and queued, but no batch is started
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := checkReports(ctx); err != nil {
+		t.Fatal(err)
+	}
+	e, ok := db.Entry(a)
+	if !ok || e.State != store.Discovered || e.Attempts != 0 {
+		t.Errorf("report is %s after %d attempts, want it queued", e.State, e.Attempts)
+	}
+}
+
+func TestBackfillQueuesReports(t *testing.T) {
+	a := clerk.NewReport("https://example.com/public_disc/ptr-pdfs/2019/20000001.pdf")
+	b := clerk.NewReport("https://example.com/public_disc/ptr-pdfs/2019/20000002.pdf")
+	setupTest(t, &fakeSource{reports: []clerk.Report{a, b}})
+
+	if err := clerk.Backfill(context.Background(), source, 2019, 2019, queueReports); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(db.Pending(time.Now())); n != 2 {
+		t.Errorf("%d reports queued, want 2", n)
+	}
+}
+
+func TestUnwantedReportsAreSkipped(t *testing.T) {
+	a := clerk.NewReport("https://example.com/public_disc/ptr-pdfs/2019/20000001.pdf")
+	a.Name = "Pelosi, Nancy"
+	b := clerk.NewReport("https://example.com/public_disc/ptr-pdfs/2019/20000002.pdf")
+	b.Name = "Doe, John"
+	setupTest(t, &fakeSource{reports: []clerk.Report{a, b}})
+	cfg.Filters.Watchlists = []watchlist.Watchlist{{Name: "leadership", Members: []string{"Nancy Pelosi"}}}
+
+	// every report is stored, and the unwanted one skipped
+	if err := queueReports([]clerk.Report{a, b}); err != nil {
+		t.Fatal(err)
+	}
+	if err := skipUnwanted(); err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := db.Entry(b); e.State != store.Skipped {
+		t.Errorf("unwanted report is %s, want %s", e.State, store.Skipped)
+	}
+	pending := db.Pending(time.Now())
+	if len(pending) != 1 || pending[0].DocID != a.DocID {
+		t.Errorf("pending %v, want only %s", pending, a.DocID)
+	}
+
+	// it is queued again once a watchlist wants it
+	cfg.Filters.Watchlists = append(cfg.Filters.Watchlists, watchlist.Watchlist{Name: "doe", Members: []string{"John Doe"}})
+	if err := skipUnwanted(); err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := db.Entry(b); e.State != store.Discovered {
+		t.Errorf("wanted report is %s, want %s", e.State, store.Discovered)
+	}
+}
+
+func TestOlderReportsAreNotified(t *testing.T) {
+	setupTest(t, &fakeSource{})
+
+	// the list was started by setup, so an older report stored later is sent
+	r := clerk.NewReport("https://example.com/public_disc/ptr-pdfs/2019/20000001.pdf")
+	if _, err := db.Enqueue(r); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveTrades(r, []trade.Trade{{Name: "A", Asset: "Apple Inc.", Ticker: "AAPL"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetState(store.Extracted, r); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(db.Unsent(store.MailingList)); n != 1 {
+		t.Fatalf("%d trades unsent, want 1", n)
+	}
+	if err := deliverReports([]clerk.Report{r}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(db.Unsent(store.MailingList)); n != 0 {
+		t.Errorf("trade of the older report was not sent")
+	}
+}
+
+func TestApprovedTradesAreNotified(t *testing.T) {
+	setupTest(t, &fakeSource{})
+	cfg.Filters.Watchlists = []watchlist.Watchlist{{Name: "chips", Tickers: []string{"NVDA"}}}
+
+	r := clerk.NewReport("https://example.com/public_disc/ptr-pdfs/" + fmt.Sprint(time.Now().Year()) + "/20000001.pdf")
+	if _, err := db.Enqueue(r); err != nil {
+		t.Fatal(err)
+	}
+	err := db.SaveTrades(r, []trade.Trade{
+		{Name: "A", Asset: "Apple Inc.", Ticker: "AAPL"},
+		{Name: "A", Asset: "NVIDIA Corp", Ticker: "NVDA", Issues: []string{"no date"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unsent := func(list string) int {
+		var n int
+		for _, t := range db.Unsent(list) {
+			if t.Accepted() {
+				n++
+			}
+		}
+		return n
+	}
+
+	// without watchlists, the accepted trade is sent to the mailing list
+	watchlists := cfg.Filters.Watchlists
+	cfg.Filters.Watchlists = nil
+	if err := deliverReports([]clerk.Report{r}); err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := db.Entry(r); e.State != store.Notified {
+		t.Errorf("report is %s, want %s", e.State, store.Notified)
+	}
+	if n := unsent(store.MailingList); n != 0 {
+		t.Errorf("%d accepted trades not sent to the mailing list", n)
+	}
+
+	// the trade approved later is sent by notify, to every list
+	trades := db.Trades(r)
+	if err := db.SetStatus(trades[1].ID, store.Approved); err != nil {
+		t.Fatal(err)
+	}
+	if n := unsent(store.MailingList); n != 1 {
+		t.Fatalf("%d approved trades not sent to the mailing list, want 1", n)
+	}
+	if err := deliverReports(nil); err != nil {
+		t.Fatal(err)
+	}
+	if n := unsent(store.MailingList); n != 0 {
+		t.Errorf("approved trade was not sent to the mailing list")
+	}
+
+	cfg.Filters.Watchlists = watchlists
+	if err := deliverReports(nil); err != nil {
+		t.Fatal(err)
+	}
+	left := db.Unsent("chips")
+	if len(left) != 1 || left[0].Ticker != "AAPL" {
+		t.Errorf("watchlist was not sent only the trade it watches, unsent: %v", left)
+	}
+}
+
+func TestIngestStoredReport(t *testing.T) {
+	setupTest(t, &fakeSource{})
+
+	archived := clerk.NewReport("https://example.com/public_disc/ptr-pdfs/2025/20000001.pdf")
+	queued := clerk.NewReport("https://example.com/public_disc/ptr-pdfs/2025/20000002.pdf")
+	if _, err := db.Enqueue(archived, queued); err != nil {
+		t.Fatal(err)
+	}
+	if err := pdfs.Put(archived, []byte("downloaded")); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	for _, file := range []string{"20000001.pdf", "20000002.pdf", "copy/20000002.pdf"} {
+		file = filepath.Join(dir, file)
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte("local "+file), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ingestFiles(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := db.Entries(store.EntryFilter{})
+	if len(entries) != 2 {
+		t.Fatalf("store has %d reports, want 2", len(entries))
+	}
+	for _, e := range entries {
+		if strings.HasPrefix(e.URL, "file:") || e.Attempts != 1 {
+			t.Errorf("report %s: %s with %d attempts, want the stored report processed once", e.DocID, e.URL, e.Attempts)
+		}
+	}
+	if data, _ := pdfs.Get(archived); string(data) != "downloaded" {
+		t.Errorf("archived PDF was replaced with %q", data)
+	}
+	if data, ok := pdfs.Get(queued); !ok || !strings.HasPrefix(string(data), "local") {
+		t.Errorf("local PDF of a queued report was not archived")
+	}
+}
+
+func TestIngestUnwantedReport(t *testing.T) {
+	setupTest(t, &fakeSource{})
+	cfg.Filters.Watchlists = []watchlist.Watchlist{{Name: "leadership", Members: []string{"Nancy Pelosi"}}}
+
+	r := clerk.NewReport("https://example.com/public_disc/ptr-pdfs/2025/20000001.pdf")
+	r.Name = "Doe, John"
+	if _, err := db.Enqueue(r); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	for _, file := range []string{"20000001.pdf", "other.pdf"} {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte("local "+file), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ingestFiles(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// the report of an unwatched member is skipped, the local one of an
+	// unknown filer is processed
+	for _, e := range db.Entries(store.EntryFilter{}) {
+		want := e.DocID == r.DocID
+		if skipped := e.State == store.Skipped && e.Attempts == 0; skipped != want {
+			t.Errorf("report %s: %s with %d attempts, skipped %v", e.DocID, e.State, e.Attempts, want)
+		}
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	for _, tc := range []struct {
+		args      []string
+		want      []string
+		name      string
+		discovery string
+	}{
+		{[]string{"a", "b"}, []string{"a", "b"}, "", "browser"},
+		{[]string{"-n", "x", "a"}, []string{"a"}, "x", "browser"},
+		{[]string{"a", "-n", "x", "b"}, []string{"a", "b"}, "x", "browser"},
+		{[]string{"a", "b", "--n=x", "-index"}, []string{"a", "b"}, "x", "index"},
+		{[]string{"-index", "a", "-index=false"}, []string{"a"}, "", "browser"},
+		{[]string{"-config=x.yaml", "a", "--config", "y.yaml"}, []string{"a"}, "", "browser"},
+		{[]string{"a", "--", "-n", "x"}, []string{"a", "-n", "x"}, "", "browser"},
+	} {
+		cfg = config.Default()
+		fs := newFlagSet("test", "", "")
+		sourceFlags(fs)
+		name := fs.String("n", "", "")
+
+		got := parseArgs(fs, tc.args, 0, math.MaxInt)
+		if !slices.Equal(got, tc.want) || *name != tc.name || cfg.Sources.Discovery != tc.discovery {
+			t.Errorf("%q: arguments %q, -n %q, discovery %s; want %q, %q, %s",
+				tc.args, got, *name, cfg.Sources.Discovery, tc.want, tc.name, tc.discovery)
+		}
+	}
+	cfg = config.Default()
+}
+
+func TestConfigArg(t *testing.T) {
+	t.Setenv(config.ENV_CONFIG, "")
+	for _, tc := range []struct {
+		args     []string
+		file     string
+		required bool
+	}{
+		{nil, config.FILE_CONFIG, false},
+		{[]string{"a", "-n", "x"}, config.FILE_CONFIG, false},
+		{[]string{"-config", "x.yaml"}, "x.yaml", true},
+		{[]string{"-config=x.yaml"}, "x.yaml", true},
+		{[]string{"--config", "x.yaml"}, "x.yaml", true},
+		{[]string{"--config=x.yaml", "a"}, "x.yaml", true},
+		{[]string{"a", "b", "-v", "-config", "x.yaml"}, "x.yaml", true},
+		{[]string{"a", "-config"}, config.FILE_CONFIG, false},
+		{[]string{"a", "--", "-config", "x.yaml"}, config.FILE_CONFIG, false},
+	} {
+		file, required := configArg(tc.args)
+		if file != tc.file || required != tc.required {
+			t.Errorf("%q: config %s, required %v; want %s, %v", tc.args, file, required, tc.file, tc.required)
+		}
+	}
+
+	t.Setenv(config.ENV_CONFIG, "env.yaml")
+	if file, required := configArg([]string{"a"}); file != "env.yaml" || !required {
+		t.Errorf("config %s, required %v; want env.yaml from the environment, required", file, required)
+	}
+}
@@ -0,0 +1,50 @@
+package names
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Fold returns s in lower case without accents, e.g. "Sánchez" becomes
+// "sanchez".
+func Fold(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(t, s)
+	if err != nil {
+		folded = s
+	}
+	return strings.ToLower(folded)
+}
+
+// Words returns the folded words of s. Punctuation separates words, so
+// "Pelosi, Nancy" and "nancy pelosi" have the same words.
+func Words(s string) []string {
+	return strings.FieldsFunc(Fold(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Match reports whether every word of pattern is a word of name, ignoring
+// case, accents, punctuation and word order. "Nancy Pelosi" matches
+// "Pelosi, Nancy" and "Hon. Nancy Pelosi", but "Pelos" matches neither.
+func Match(pattern, name string) bool {
+	want := Words(pattern)
+	if len(want) == 0 {
+		return false
+	}
+
+	have := make(map[string]bool)
+	for _, w := range Words(name) {
+		have[w] = true
+	}
+	for _, w := range want {
+		if !have[w] {
+			return false
+		}
+	}
+	return true
+}
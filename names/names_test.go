@@ -0,0 +1,59 @@
+package names
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		s, want string
+	}{
+		{"Sánchez", "sanchez"},
+		{"MÜLLER", "muller"},
+		{"Ocasio-Cortez", "ocasio-cortez"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Fold(tt.s); got != tt.want {
+			t.Errorf("Fold(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestWords(t *testing.T) {
+	if got, want := Words("Pelosi, Hon.. Nancy"), []string{"pelosi", "hon", "nancy"}; !slices.Equal(got, want) {
+		t.Errorf("words %q, want %q", got, want)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		filer   string
+		want    bool
+	}{
+		{"same name", "Nancy Pelosi", "Nancy Pelosi", true},
+		{"last name first", "Nancy Pelosi", "Pelosi, Nancy", true},
+		{"title", "Nancy Pelosi", "Hon. Nancy Pelosi", true},
+		{"case", "nancy pelosi", "PELOSI, NANCY", true},
+		{"accents", "Linda Sanchez", "Sánchez, Linda T.", true},
+		{"accents in the pattern", "Linda Sánchez", "Sanchez, Linda", true},
+		{"middle name", "Michael McCaul", "McCaul, Hon.. Michael T.", true},
+		{"last name only", "Pelosi", "Pelosi, Nancy", true},
+		{"hyphenated", "Ocasio-Cortez", "Alexandria Ocasio-Cortez", true},
+		{"part of a word", "Pelos", "Pelosi, Nancy", false},
+		{"missing word", "Nancy Pelosi", "Pelosi, Paul", false},
+		{"more words than the name", "Nancy Patricia Pelosi", "Pelosi, Nancy", false},
+		{"empty pattern", "", "Pelosi, Nancy", false},
+		{"only punctuation", ".,", "Pelosi, Nancy", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Match(tt.pattern, tt.filer); got != tt.want {
+				t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.filer, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package openai
+
+import (
+	"bytes"
+	"clerk_trades/clerk"
+	"clerk_trades/extract"
+	"clerk_trades/pdftext"
+	"clerk_trades/trade"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultBaseURL = "http://localhost:11434/v1" // local Ollama
+	DefaultModel   = "llama3.1"
+
+	// maxAsks is how often a report is sent before an answer that does not
+	// match the schema fails its extraction.
+	maxAsks = 3
+)
+
+// ErrNoText is returned for reports without a text layer, like scanned or
+// handwritten filings, which only the gemini backend can read.
+var ErrNoText = errors.New("report has no text layer, scanned filings need the gemini extractor")
+
+// Extractor extracts trades through any OpenAI-compatible chat completions
+// endpoint, e.g. OpenAI itself, Ollama or a llama.cpp server. The text layer
+// of the PDF is sent as the prompt, so local models without PDF support work.
+// Scanned filings have no text layer and fail with ErrNoText, so unlike
+// Gemini it can only read reports whose text ptr.Parse did not understand.
+type Extractor struct {
+	BaseURL string
+	APIKey  string // optional for local servers
+	Model   string
+	Client  *http.Client
+}
+
+// New creates an extractor for the endpoint at baseURL. Empty values use
+// DefaultBaseURL and DefaultModel.
+func New(baseURL, apiKey, model string) *Extractor {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	if model == "" {
+		model = DefaultModel
+	}
+	return &Extractor{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		APIKey:  apiKey,
+		Model:   model,
+		Client:  &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+func (e *Extractor) Name() string {
+	return "openai/" + e.Model
+}
+
+func (e *Extractor) Close() error {
+	return nil
+}
+
+type message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model       string    `json:"model"`
+	Messages    []message `json:"messages"`
+	Temperature float64   `json:"temperature"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message message `json:"message"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+func (e *Extractor) Extract(ctx context.Context, content []byte, report clerk.Report) ([]trade.Trade, error) {
+	text, err := pdftext.Text(content)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil, ErrNoText
+	}
+
+	messages := []message{
+		{Role: "system", Content: extract.Prompt},
+		{Role: "user", Content: "create JSON with the very important instructions. answer with the JSON array only.\n\n" + text},
+	}
+
+	// re-ask when the answer violates the schema
+	for ask := 1; ask <= maxAsks; ask++ {
+		var out string
+		out, err = e.complete(ctx, messages)
+		if err != nil {
+			return nil, err
+		}
+
+		var trades []trade.Trade
+		trades, err = extract.ParseTrades(out)
+		if err == nil {
+			return trades, nil
+		}
+		if !errors.Is(err, extract.ErrSchema) {
+			return nil, err
+		}
+
+		log.Printf("report %s: %v. asking again (%d/%d).\n", report.URL, err, ask, maxAsks)
+		messages = append(messages,
+			message{Role: "assistant", Content: out},
+			message{Role: "user", Content: fmt.Sprintf("Your answer is invalid: %v. Answer again with the corrected JSON array only.", err)},
+		)
+	}
+	return nil, err
+}
+
+func (e *Extractor) complete(ctx context.Context, messages []message) (string, error) {
+	body, err := json.Marshal(chatRequest{
+		Model:       e.Model,
+		Messages:    messages,
+		Temperature: 0,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", e.BaseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.APIKey)
+	}
+
+	resp, err := e.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate content: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %v", err)
+	}
+
+	var cr chatResponse
+	if err := json.Unmarshal(data, &cr); err != nil {
+		return "", fmt.Errorf("failed to decode response (%s): %v", resp.Status, err)
+	}
+	if cr.Error != nil {
+		return "", fmt.Errorf("failed to generate content: %s", cr.Error.Message)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to generate content: %s", resp.Status)
+	}
+	if len(cr.Choices) == 0 {
+		return "", fmt.Errorf("no output data from model")
+	}
+	return cr.Choices[0].Message.Content, nil
+}
@@ -0,0 +1,156 @@
+package openai
+
+import (
+	"clerk_trades/clerk"
+	"clerk_trades/extract"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testPDF builds a one-page PDF whose text layer holds lines, or none.
+func testPDF(lines ...string) []byte {
+	var content strings.Builder
+	if len(lines) > 0 {
+		content.WriteString("BT /F1 12 Tf 14 TL 72 720 Td")
+		for _, line := range lines {
+			fmt.Fprintf(&content, " (%s) Tj T*", line)
+		}
+		content.WriteString(" ET")
+	}
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}
+
+	var pdf strings.Builder
+	pdf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = pdf.Len()
+		fmt.Fprintf(&pdf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := pdf.Len()
+	fmt.Fprintf(&pdf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&pdf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&pdf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return []byte(pdf.String())
+}
+
+// chatServer answers every chat completion with the next of answers and
+// records the requests.
+func chatServer(t *testing.T, answers ...string) (*httptest.Server, *[]chatRequest) {
+	t.Helper()
+	var requests []chatRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer key" {
+			t.Errorf("Authorization is %q", got)
+		}
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		requests = append(requests, req)
+
+		if len(answers) == 0 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(map[string]any{"error": map[string]string{"message": "rate limited"}})
+			return
+		}
+		var resp chatResponse
+		resp.Choices = append(resp.Choices, struct {
+			Message message `json:"message"`
+		}{message{Role: "assistant", Content: answers[0]}})
+		answers = answers[1:]
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+const answer = "```json\n" + `[{"Name": "Nancy Pelosi", "Owner": "SP", "Asset": "Apple Inc. (AAPL) [ST]", "Ticker": "AAPL",
+"Type": "Purchase", "Date": "01/02/2025", "Filed": "01/10/2025", "Amount": "$1,001 - $15,000", "Cap": false,
+"AssetType": "ST", "Description": "", "OptionType": "", "Strike": 0, "Expiration": ""}]` + "\n```"
+
+func TestExtract(t *testing.T) {
+	srv, requests := chatServer(t, answer)
+	e := New(srv.URL+"/v1/", "key", "test")
+
+	trades, err := e.Extract(context.Background(), testPDF("Filer Information", "Name: Hon. Nancy Pelosi"), clerk.Report{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 1 || trades[0].Ticker != "AAPL" || trades[0].Owner != "SP" {
+		t.Errorf("extracted %+v", trades)
+	}
+
+	if len(*requests) != 1 {
+		t.Fatalf("sent %d requests, want 1", len(*requests))
+	}
+	req := (*requests)[0]
+	if req.Model != "test" || len(req.Messages) != 2 || req.Messages[0].Role != "system" {
+		t.Errorf("sent %+v", req)
+	}
+	if !strings.Contains(req.Messages[1].Content, "Name: Hon. Nancy Pelosi") {
+		t.Errorf("text layer was not sent: %q", req.Messages[1].Content)
+	}
+}
+
+func TestExtractAsksAgain(t *testing.T) {
+	srv, requests := chatServer(t, `[{"Name": "Nancy Pelosi"}]`, answer)
+	e := New(srv.URL+"/v1", "key", "test")
+
+	trades, err := e.Extract(context.Background(), testPDF("Filer Information"), clerk.Report{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 1 {
+		t.Errorf("extracted %d trades, want 1", len(trades))
+	}
+	if len(*requests) != 2 {
+		t.Fatalf("sent %d requests, want 2", len(*requests))
+	}
+	// the second request holds the invalid answer and why it is invalid
+	if msgs := (*requests)[1].Messages; len(msgs) != 4 || msgs[2].Role != "assistant" || !strings.Contains(msgs[3].Content, "invalid") {
+		t.Errorf("second request is %+v", msgs)
+	}
+}
+
+func TestExtractErrors(t *testing.T) {
+	srv, requests := chatServer(t, "no JSON here", "[]x", `[{"Name": 1}]`)
+	e := New(srv.URL+"/v1", "key", "test")
+
+	if _, err := e.Extract(context.Background(), testPDF(), clerk.Report{}); !errors.Is(err, ErrNoText) {
+		t.Errorf("report without text layer returned %v, want %v", err, ErrNoText)
+	}
+	if len(*requests) != 0 {
+		t.Errorf("report without text layer was sent")
+	}
+
+	// invalid answers are given up on after maxAsks
+	if _, err := e.Extract(context.Background(), testPDF("Filer Information"), clerk.Report{}); !errors.Is(err, extract.ErrSchema) {
+		t.Errorf("invalid answers returned %v, want %v", err, extract.ErrSchema)
+	}
+	if len(*requests) != maxAsks {
+		t.Errorf("sent %d requests, want %d", len(*requests), maxAsks)
+	}
+
+	_, err := e.Extract(context.Background(), testPDF("Filer Information"), clerk.Report{})
+	if err == nil || !strings.Contains(err.Error(), "rate limited") {
+		t.Errorf("error response returned %v", err)
+	}
+}
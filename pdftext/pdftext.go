@@ -0,0 +1,53 @@
+package pdftext
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// Lines returns the text layer of a PDF as lines, top to bottom and page by
+// page. Scanned filings without a text layer return no lines.
+func Lines(data []byte) ([]string, error) {
+	r, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pdf: %v", err)
+	}
+
+	var lines []string
+	for i := 1; i <= r.NumPage(); i++ {
+		page := r.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+
+		rows, err := page.GetTextByRow()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read text of page %d: %v", i, err)
+		}
+
+		for _, row := range rows {
+			var words []string
+			for _, text := range row.Content {
+				words = append(words, text.S)
+			}
+			line := strings.Join(strings.Fields(strings.Join(words, " ")), " ")
+			if line != "" {
+				lines = append(lines, line)
+			}
+		}
+	}
+
+	return lines, nil
+}
+
+// Text returns the text layer of a PDF, one line per text row.
+func Text(data []byte) (string, error) {
+	lines, err := Lines(data)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(lines, "\n"), nil
+}
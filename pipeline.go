@@ -0,0 +1,543 @@
+package main
+
+import (
+	"clerk_trades/clerk"
+	"clerk_trades/download"
+	"clerk_trades/email"
+	"clerk_trades/extract"
+	"clerk_trades/gemini"
+	"clerk_trades/openai"
+	"clerk_trades/ptr"
+	"clerk_trades/store"
+	"clerk_trades/trade"
+	"clerk_trades/utils"
+	"clerk_trades/watchlist"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// checkReports discovers new reports of source, queues them, processes the
+// queue and notifies the watchlists. When ctx is done, the batch in progress
+// is finished and notified, and the rest of the queue waits for the next check.
+func checkReports(ctx context.Context) error {
+	links, _ := utils.ReadJSON[[]clerk.Report](clerk.Path(clerk.FILE_LINKS))
+	if verbose {
+		log.Printf("loaded %d reports.\n", len(links))
+	}
+
+	log.Println("checking for new reports.")
+	reports, err := clerk.SiteCheck(ctx, source, links)
+	if err != nil {
+		return err
+	}
+
+	// queued before they are saved, see queueReports
+	if len(reports) > 0 {
+		if err := queueReports(reports); err != nil {
+			return err
+		}
+		if err := clerk.SaveReports(append(links, reports...)); err != nil {
+			return err
+		}
+	}
+	if err := drainQueue(ctx); err != nil {
+		return err
+	}
+	return notifyTrades()
+}
+
+// queueReports resolves the members of newly discovered reports and queues
+// them. drainQueue skips the ones no watchlist wants. Both checkReports and
+// clerk.Backfill queue reports before they save them to clerk.FILE_LINKS, so a
+// crash in between only rediscovers them instead of losing them.
+func queueReports(reports []clerk.Report) error {
+	for i, r := range reports {
+		r.MemberID = members.ID(r.Name, r.Office)
+		reports[i] = r
+	}
+	added, err := db.Enqueue(reports...)
+	if err != nil {
+		return err
+	}
+	log.Printf("queued %d new reports.\n", added)
+	return nil
+}
+
+// skipUnwanted skips the queued reports no watchlist wants, and queues the
+// skipped reports a watchlist wants again, e.g. after a watchlist was added.
+func skipUnwanted() error {
+	var skip, unskip []clerk.Report
+	for _, e := range db.Entries(store.EntryFilter{States: []store.State{store.Discovered, store.Skipped}}) {
+		wanted := watchlist.Wants(cfg.Filters.Watchlists, e.Report)
+		switch {
+		case e.State == store.Discovered && !wanted:
+			skip = append(skip, e.Report)
+		case e.State == store.Skipped && wanted:
+			unskip = append(unskip, e.Report)
+		}
+	}
+
+	if len(skip) > 0 {
+		if err := db.SetState(store.Skipped, skip...); err != nil {
+			return err
+		}
+		if verbose {
+			log.Printf("skipped %d reports no watchlist wants.\n", len(skip))
+		}
+	}
+	if len(unskip) > 0 {
+		if err := db.SetState(store.Discovered, unskip...); err != nil {
+			return err
+		}
+		log.Printf("queued %d skipped reports the watchlists want now.\n", len(unskip))
+	}
+	return nil
+}
+
+// listTrades prints the trades of the last n known reports. Trades are read
+// from the store; only reports that were never extracted are sent to the extractor.
+func listTrades(n int) error {
+	links, _ := utils.ReadJSON[[]clerk.Report](clerk.Path(clerk.FILE_LINKS))
+	if len(links) == 0 {
+		return fmt.Errorf("no report links stored. run the scan command first, to get links from clerk site")
+	}
+	files := links
+	if n > 0 {
+		if len(files)-1 >= n {
+			files = files[len(files)-n:] // Keep only the last n files
+		}
+	}
+
+	if _, err := db.Enqueue(files...); err != nil {
+		return err
+	}
+	var missing []store.Entry
+	for _, r := range files {
+		if e, ok := db.Entry(r); ok && !e.HasTrades() {
+			missing = append(missing, e)
+		}
+	}
+	if len(missing) > 0 {
+		extractReports(missing)
+	}
+
+	trades := db.Trades(files...)
+	if len(trades) == 0 {
+		log.Println("no trades found.")
+		return nil
+	}
+	log.Print("\r\n", trade.PrintTrades(acceptedTrades(trades)))
+
+	if cfg.Notifiers.Email.Enabled {
+		if err := notify("TRADES", acceptedTrades(trades)); err != nil {
+			return err
+		}
+		if verbose {
+			log.Println("trade reports have been e-mailed.")
+		}
+		var sent []clerk.Report
+		for _, r := range files {
+			if e, ok := db.Entry(r); ok && e.HasTrades() {
+				sent = append(sent, r)
+			}
+		}
+		setState(store.Notified, sent...)
+		var ids []int
+		for _, t := range trades {
+			if t.Accepted() {
+				ids = append(ids, t.ID)
+			}
+		}
+		if err := db.MarkSent(store.MailingList, ids...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// acceptedTrades returns the stored trades that passed validation or review.
+func acceptedTrades(stored []store.Trade) []trade.Trade {
+	trades := make([]trade.Trade, 0, len(stored))
+	for _, t := range stored {
+		if t.Accepted() {
+			trades = append(trades, t.Trade)
+		}
+	}
+	return trades
+}
+
+// drainQueue processes the due reports of the queue in batches of
+// the configured batch size. Every report moves through the store states, so failed reports
+// are retried on later checks and a crash resumes where it stopped. No batch is
+// started once ctx is done.
+func drainQueue(ctx context.Context) error {
+	if err := skipUnwanted(); err != nil {
+		return err
+	}
+	pending := db.Pending(time.Now())
+	if len(pending) == 0 {
+		log.Println("nothing new to process.")
+		return nil
+	}
+	log.Printf("%d reports in queue. processing in batches of %d.\n", len(pending), cfg.Schedule.Batch)
+
+	for _, e := range pending {
+		if e.State == store.Failed {
+			log.Printf("retrying report %s (attempt %d, failed in %s: %s).\n", e.URL, e.Attempts+1, e.FailedIn, e.LastError)
+		}
+	}
+
+	for start := 0; start < len(pending); start += cfg.Schedule.Batch {
+		if ctx.Err() != nil {
+			log.Printf("stopped processing. %d left in queue.\n", db.Len())
+			return nil
+		}
+		end := min(start+cfg.Schedule.Batch, len(pending))
+
+		if err := processReports(pending[start:end]); err != nil {
+			return err
+		}
+		log.Printf("processed %d/%d queued reports. %d left in queue.\n", end, len(pending), db.Len())
+	}
+
+	return nil
+}
+
+// setState records the state of reports in the store.
+func setState(state store.State, reports ...clerk.Report) {
+	if len(reports) == 0 {
+		return
+	}
+	if err := db.SetState(state, reports...); err != nil {
+		log.Println("error:", err)
+	}
+}
+
+// failReports records a failed step of reports in the store.
+func failReports(cause error, reports ...clerk.Report) {
+	if len(reports) == 0 {
+		return
+	}
+	if err := db.Fail(cause, reports...); err != nil {
+		log.Println("error:", err)
+	}
+}
+
+// processReports extracts the trades of the entries that have none stored
+// yet, prints and e-mails the trades of all extracted entries and marks them
+// notified. Failed steps are recorded in the store.
+func processReports(entries []store.Entry) error {
+	var extract []store.Entry
+	var ready []clerk.Report
+	for _, e := range entries {
+		if e.HasTrades() {
+			ready = append(ready, e.Report)
+		} else {
+			extract = append(extract, e)
+		}
+	}
+	return deliverReports(append(ready, extractReports(extract)...))
+}
+
+// deliverReports prints the accepted trades of the extracted reports, marks
+// them notified and notifies the lists of the trades they were not sent yet.
+func deliverReports(ready []clerk.Report) error {
+	if len(ready) > 0 {
+		log.Print("\r\n", trade.PrintTrades(acceptedTrades(db.Trades(ready...))))
+		setState(store.Notified, ready...)
+	}
+	return notifyTrades()
+}
+
+// notifyTrades e-mails every watchlist the accepted trades it watches in the
+// extracted reports that were not sent to it yet, or all of them to the
+// mailing list without watchlists. Trades approved in review are sent with
+// the next call, and so are the trades of a list that failed to be e-mailed.
+// Without e-mail, the trades are printed and count as sent. Lists start with
+// the reports of the current filing years, see startLists.
+func notifyTrades() error {
+	// the reports of the unsent trades are read once, when a list needs them
+	reports := make(map[string]clerk.Report)
+	report := func(docID string) clerk.Report {
+		r, ok := reports[docID]
+		if !ok {
+			e, _ := db.Report(docID)
+			r = e.Report
+			reports[docID] = r
+		}
+		return r
+	}
+
+	if len(cfg.Filters.Watchlists) == 0 {
+		return notifyList(store.MailingList, "TRADES", report, func(clerk.Report, trade.Trade) bool { return true })
+	}
+	var errs []error
+	for _, w := range cfg.Filters.Watchlists {
+		if err := notifyList(w.Name, "TRADES - "+w.Name, report, w.Watches); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// startLists starts the lists that are notified: the watchlists, or the
+// mailing list without them. A new list is not sent the trades stored before
+// of reports of earlier filing years, but it is sent those of older reports
+// stored later, e.g. by ingest or backfill.
+func startLists() error {
+	lists := []string{store.MailingList}
+	if len(cfg.Filters.Watchlists) > 0 {
+		lists = lists[:0]
+		for _, w := range cfg.Filters.Watchlists {
+			lists = append(lists, w.Name)
+		}
+	}
+	for _, list := range lists {
+		if err := db.StartList(list, clerk.Years(time.Now())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notifyList e-mails list with subject the accepted trades of extracted
+// reports it watches and was not sent yet, and records them as sent. report
+// returns the report of a trade.
+func notifyList(list, subject string, report func(docID string) clerk.Report, watches func(clerk.Report, trade.Trade) bool) error {
+	var ids []int
+	var trades []trade.Trade
+	for _, t := range db.Unsent(list) {
+		if watches(report(t.ReportID), t.Trade) {
+			ids = append(ids, t.ID)
+			trades = append(trades, t.Trade)
+		}
+	}
+	if len(trades) == 0 {
+		return nil
+	}
+
+	if list != store.MailingList {
+		log.Printf("watchlist %s: %d new trades:\r\n%s", list, len(trades), trade.PrintTrades(trades))
+	}
+	if cfg.Notifiers.Email.Enabled {
+		if err := notify(subject, trades); err != nil {
+			return fmt.Errorf("failed to notify %s: %w", subject, err)
+		}
+		log.Printf("%d trades have been e-mailed with subject %s.\n", len(trades), subject)
+	}
+	return db.MarkSent(list, ids...)
+}
+
+// extractReports fetches the reports and stores the trades the extractor finds
+// in each of them. It returns the reports that were extracted.
+func extractReports(entries []store.Entry) []clerk.Report {
+	if len(entries) == 0 {
+		return nil
+	}
+	log.Printf("allocating space for %d reports in memory.\n", len(entries))
+
+	reports := make([]clerk.Report, 0, len(entries))
+	for _, e := range entries {
+		reports = append(reports, e.Report)
+	}
+	fetched := fetchReports(reports)
+
+	var contents [][]byte
+	reports = reports[:0]
+	for _, e := range entries {
+		res := fetched[e.URL]
+		if res.Err == nil && len(res.Data) == 0 {
+			res.Err = fmt.Errorf("empty report")
+		}
+		if res.Err != nil {
+			log.Printf("failed to fetch content for report %s: %v\n", e.URL, res.Err)
+			failReports(res.Err, e.Report)
+			continue
+		}
+		setState(store.Downloaded, e.Report)
+		contents = append(contents, res.Data)
+		reports = append(reports, e.Report)
+	}
+	if len(reports) == 0 {
+		return nil
+	}
+
+	ex, parser, err := newExtractor()
+	if err != nil {
+		log.Println("error:", err)
+		failReports(err, reports...)
+		return nil
+	}
+	defer ex.Close()
+
+	results := extract.ProcessReports(ex, contents, reports)
+
+	var extracted []clerk.Report
+	for i, res := range results {
+		if res.Err != nil {
+			log.Printf("failed to extract trades of report %s: %v\n", res.Report.URL, res.Err)
+			failReports(res.Err, res.Report)
+			continue
+		}
+		h, ok := parser.Header(res.Report)
+		if !ok {
+			h = ptr.ReadHeader(contents[i])
+		}
+		disclosed := disclosedDate(res.Report, h)
+		var flagged, late int
+		for i := range res.Trades {
+			res.Trades[i].DisclosedDate = disclosed
+			securities.Apply(&res.Trades[i])
+			res.Trades[i].Issues = rules.Validate(res.Trades[i], res.Report.Year)
+			if len(res.Trades[i].Issues) > 0 {
+				flagged++
+			}
+			if res.Trades[i].Late() {
+				late++
+			}
+		}
+		if flagged > 0 {
+			log.Printf("report %s: %d trades failed validation and wait for review.\n", res.Report.URL, flagged)
+		}
+		if late > 0 {
+			log.Printf("report %s: %d trades were disclosed after %d days.\n", res.Report.URL, late, trade.DisclosureDays)
+		}
+
+		if h.Amendment || h.Amends != "" {
+			if err := db.SetAmendment(res.Report, h.Amends); err != nil {
+				failReports(err, res.Report)
+				continue
+			}
+		}
+		if err := db.SaveTrades(res.Report, res.Trades); err != nil {
+			failReports(err, res.Report)
+			continue
+		}
+		logAmendment(res.Report)
+		setState(store.Extracted, res.Report)
+		extracted = append(extracted, res.Report)
+	}
+
+	return extracted
+}
+
+// disclosedDate returns the date report r was filed: its filing date in the
+// Clerk index, or else the date it was signed. It is zero if neither is known.
+func disclosedDate(r clerk.Report, h ptr.Header) time.Time {
+	for _, date := range []string{r.FilingDate, h.Signed} {
+		if d, err := trade.ParseDate(date); err == nil {
+			return d
+		}
+	}
+	return time.Time{}
+}
+
+// logAmendment logs which report an amendment amends, how many of its
+// trades are only restated and how many trades of the amended report it
+// supersedes.
+func logAmendment(r clerk.Report) {
+	e, ok := db.Entry(r)
+	if !ok || !e.Amendment() {
+		return
+	}
+	var restated int
+	trades := db.Trades(r)
+	for _, t := range trades {
+		if t.Restates != 0 {
+			restated++
+		}
+	}
+	original := e.Amends
+	if original == "" {
+		log.Printf("report %s amends an unknown report: %d of %d trades are restated.\n", r.DocID, restated, len(trades))
+		return
+	}
+	superseded := len(db.Query(store.TradeFilter{ReportID: original, Superseded: r.DocID}))
+	log.Printf("report %s amends %s: %d of %d trades are restated, %d trades of %s are superseded.\n",
+		r.DocID, original, restated, len(trades), superseded, original)
+}
+
+// newExtractor creates the extraction backend selected with -extractor,
+// with its results cached by PDF content unless the cache is disabled. It
+// also returns the text layer parser in front of the backend, which keeps
+// the headers of the reports it read.
+func newExtractor() (extract.Extractor, *ptr.Extractor, error) {
+	parser, err := newBackend()
+	if err != nil {
+		return nil, nil, err
+	}
+	if noCache {
+		return parser, parser, nil
+	}
+	ex, err := extract.NewCache(parser, storagePath(extract.DIR_CACHE))
+	if err != nil {
+		return nil, nil, err
+	}
+	return ex, parser, nil
+}
+
+// newBackend creates the extraction backend selected with -extractor.
+// Reports are parsed from their text layer first; the backend only reads
+// the ones that cannot be parsed. Gemini is created for the first of them,
+// so without GEMINI_API_KEY only those fail.
+func newBackend() (*ptr.Extractor, error) {
+	x := cfg.Extractor
+	switch x.Backend {
+	case "ptr":
+		return ptr.New(nil), nil
+	case "gemini":
+		model := x.Gemini.Model
+		if model == "" {
+			model = gemini.DefaultModel
+		}
+		return ptr.New(extract.NewLazy("gemini/"+model, func() (extract.Extractor, error) {
+			return gemini.New(x.Gemini.APIKey, model)
+		})), nil
+	case "openai":
+		return ptr.New(openai.New(x.OpenAI.BaseURL, x.OpenAI.APIKey, x.OpenAI.Model)), nil
+	}
+	return nil, fmt.Errorf("unknown extractor %q", x.Backend)
+}
+
+// notify e-mails trades to the mailing list with subject.
+func notify(subject string, trades []trade.Trade) error {
+	emailBody, err := email.GenerateEmailBody(trades)
+	if err != nil {
+		return err
+	}
+	if email.Mailgun.Paid {
+		return email.SendHTMLToMailingList(subject, emailBody)
+	}
+	return email.SendHTMLTo(subject, emailBody)
+}
+
+// fetchReports returns the PDFs of reports keyed by report URL. Archived
+// reports are read from the archive, the others are downloaded and archived.
+func fetchReports(reports []clerk.Report) map[string]download.Result {
+	results := make(map[string]download.Result, len(reports))
+
+	var missing []clerk.Report
+	for _, r := range reports {
+		if content, ok := pdfs.Get(r); ok {
+			if verbose {
+				log.Printf("report %s read from archive.\n", r.URL)
+			}
+			results[r.URL] = download.Result{Report: r, Data: content}
+			continue
+		}
+		missing = append(missing, r)
+	}
+
+	for link, res := range downloader.Fetch(context.Background(), missing) {
+		if res.Err == nil {
+			if err := pdfs.Put(res.Report, res.Data); err != nil {
+				log.Println("error:", err)
+			}
+		}
+		results[link] = res
+	}
+	return results
+}
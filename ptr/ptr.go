@@ -0,0 +1,291 @@
+package ptr
+
+import (
+	"clerk_trades/clerk"
+	"clerk_trades/extract"
+	"clerk_trades/pdftext"
+	"clerk_trades/trade"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Version of the parser. It is part of the extractor name, so cached results
+// of an older parser are not used.
+const Version = "1"
+
+var (
+	ErrNoText   = errors.New("report has no text layer")
+	ErrUnparsed = errors.New("report layout not recognized")
+)
+
+var verbose bool
+
+func SetVerbose(v bool) {
+	verbose = v
+}
+
+var (
+	// a transaction row: owner, asset, type, date, notification date, amount
+	rowRe = regexp.MustCompile(`^(?:(SP|JT|DC)\s+)?(.+?)\s+(S \(partial\)|P|S|E)\s+(\d{1,2}/\d{1,2}/\d{4})\s+(\d{1,2}/\d{1,2}/\d{4})\s+(\$[\d,]+\s*-\s*\$[\d,]+|Spouse/DC Over \$[\d,]+|Over \$[\d,]+)`)
+	// ticker in the asset name, e.g. "Apple Inc. (AAPL) [ST]"
+	tickerRe = regexp.MustCompile(`\(([A-Z][A-Z0-9.\-]{0,9})\)`)
+	// details printed below a row: filing status, subholding of, description,
+	// location and comments
+	detailRe = regexp.MustCompile(`^(F\s?S|S\s?O|D|L|C)\s?:`)
+	descRe   = regexp.MustCompile(`^D\s?:\s*(.*)$`)
+	// column headers, repeated on every page
+	headerRe = regexp.MustCompile(`^(ID Owner Asset Transaction|Type|Date Notification|Date|Amount Cap\.|Gains >|\$200\?)$`)
+	// header line of an amended report, and the filing it amends if named
+	amendRe    = regexp.MustCompile(`(?i)\bamend(?:ment|ed|s)?\b`)
+	filingIDRe = regexp.MustCompile(`\b(\d{8})\b`)
+	// signature at the end of the report, with the date it was filed
+	signedRe = regexp.MustCompile(`^Digitally Signed:.*,\s*(\d{1,2}/\d{1,2}/\d{4})$`)
+	// title the name is prefixed with
+	honorificRe = regexp.MustCompile(`^(?i:(hon|mr|mrs|ms|dr)\.?\s+)+`)
+)
+
+// checkbox glyphs of the "Cap. Gains > $200?" column in the text layer
+const (
+	checked   = "gfedcb"
+	unchecked = "gfedc"
+)
+
+// maximum lines a single transaction row may wrap over
+const maxRowLines = 8
+
+var types = map[string]trade.TxType{
+	"P":           trade.Purchase,
+	"S":           trade.Sale,
+	"S (partial)": trade.PartialSale,
+	"E":           trade.Exchange,
+}
+
+// Parse reads the trades from the text layer of an electronically filed
+// Periodic Transaction Report. It returns ErrUnparsed when the text does not
+// follow the layout of those reports.
+func Parse(lines []string) ([]trade.Trade, error) {
+	var name string
+	var trades []trade.Trade
+	var buf []string
+	inTable := false
+
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+
+		if !inTable {
+			if v, ok := strings.CutPrefix(line, "Name:"); ok && name == "" {
+				name = strings.TrimSpace(honorificRe.ReplaceAllString(strings.TrimSpace(v), ""))
+			}
+			if line == "$200?" {
+				inTable = true
+			}
+			continue
+		}
+
+		// end of the transactions table
+		if strings.HasPrefix(line, "* For the complete list") || strings.HasPrefix(line, "I CERTIFY") {
+			break
+		}
+
+		// the description of the last row, which holds options details
+		if m := descRe.FindStringSubmatch(line); m != nil && len(buf) == 0 && len(trades) > 0 {
+			if last := &trades[len(trades)-1]; last.Description == "" {
+				last.Description = m[1]
+			}
+			continue
+		}
+
+		if headerRe.MatchString(line) || detailRe.MatchString(line) {
+			continue
+		}
+
+		// a checkbox on a line of its own belongs to the last row
+		if line == checked || line == unchecked {
+			if len(buf) == 0 && len(trades) > 0 && line == checked {
+				trades[len(trades)-1].Cap = true
+			}
+			continue
+		}
+
+		buf = append(buf, line)
+		text := strings.Join(buf, " ")
+		m := rowRe.FindStringSubmatchIndex(text)
+		if m == nil {
+			if len(buf) > maxRowLines {
+				return nil, fmt.Errorf("%w: no transaction in %q", ErrUnparsed, text)
+			}
+			continue
+		}
+
+		group := func(i int) string {
+			if m[2*i] < 0 {
+				return ""
+			}
+			return text[m[2*i]:m[2*i+1]]
+		}
+
+		t := trade.Trade{
+			Name:   name,
+			Owner:  group(1),
+			Asset:  strings.TrimSpace(group(2)),
+			Type:   types[group(3)],
+			Date:   group(4),
+			Filed:  group(5),
+			Amount: strings.Join(strings.Fields(group(6)), " "),
+		}
+		if t.Owner == "" {
+			t.Owner = "Self"
+		}
+		if t.Asset == "" || strings.Contains(t.Asset, ":") {
+			return nil, fmt.Errorf("%w: unexpected asset %q", ErrUnparsed, t.Asset)
+		}
+		if tm := tickerRe.FindAllStringSubmatch(t.Asset, -1); tm != nil {
+			t.Ticker = tm[len(tm)-1][1]
+			t.TickerSource = trade.TickerFiling
+		}
+
+		rest := strings.TrimSpace(text[m[1]:])
+		if strings.HasPrefix(rest, checked) {
+			t.Cap = true
+		}
+		rest = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(rest, checked), unchecked))
+
+		trades = append(trades, t)
+		buf = nil
+		if rest != "" {
+			buf = append(buf, rest)
+		}
+	}
+
+	if name == "" || len(trades) == 0 {
+		return nil, ErrUnparsed
+	}
+	return trades, nil
+}
+
+// Header is what the header of a report, above the transactions table, and
+// its signature tell about the filing.
+type Header struct {
+	Amendment bool
+	Amends    string // filing ID of the amended report, if named
+	Signed    string // date the report was signed and filed, e.g. "01/17/2025"
+}
+
+// ReadHeader reads the header from the text layer of a report. Reports
+// without a text layer have an empty header.
+func ReadHeader(content []byte) Header {
+	lines, err := pdftext.Lines(content)
+	if err != nil {
+		return Header{}
+	}
+	return readHeader(lines)
+}
+
+func readHeader(lines []string) Header {
+	var h Header
+	inHeader := true
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if m := signedRe.FindStringSubmatch(line); m != nil {
+			h.Signed = m[1]
+			continue
+		}
+		if !inHeader {
+			continue
+		}
+		if line == "$200?" || strings.HasPrefix(line, "ID Owner Asset") {
+			inHeader = false
+			continue
+		}
+		if amendRe.MatchString(line) {
+			h.Amendment = true
+			if m := filingIDRe.FindStringSubmatch(line); m != nil && h.Amends == "" {
+				h.Amends = m[1]
+			}
+		}
+	}
+	return h
+}
+
+// Extractor parses the text layer of a report and only hands reports it
+// cannot parse, like scanned or handwritten filings, to Fallback.
+type Extractor struct {
+	Fallback extract.Extractor // may be nil
+
+	mu      sync.Mutex
+	headers map[string]Header // by DocID
+}
+
+func New(fallback extract.Extractor) *Extractor {
+	return &Extractor{Fallback: fallback}
+}
+
+func (e *Extractor) Name() string {
+	if e.Fallback == nil {
+		return "ptr/" + Version
+	}
+	return "ptr/" + Version + "+" + e.Fallback.Name()
+}
+
+func (e *Extractor) Close() error {
+	if e.Fallback == nil {
+		return nil
+	}
+	return e.Fallback.Close()
+}
+
+// Header returns the header of report r, which Extract reads with its text
+// layer. It is not known for reports Extract was not called for, e.g. ones
+// answered from a cache in front of it.
+func (e *Extractor) Header(r clerk.Report) (Header, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	h, ok := e.headers[r.DocID]
+	return h, ok
+}
+
+func (e *Extractor) setHeader(r clerk.Report, h Header) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.headers == nil {
+		e.headers = make(map[string]Header)
+	}
+	e.headers[r.DocID] = h
+}
+
+func (e *Extractor) Extract(ctx context.Context, content []byte, report clerk.Report) ([]trade.Trade, error) {
+	lines, err := pdftext.Lines(content)
+	e.setHeader(report, readHeader(lines))
+	if err == nil && len(lines) == 0 {
+		err = ErrNoText
+	}
+	if err == nil {
+		trades, perr := Parse(lines)
+		if perr == nil {
+			if verbose {
+				log.Printf("parsed %d trades from text of report %s.\n", len(trades), report.URL)
+			}
+			return trades, nil
+		}
+		err = perr
+	}
+
+	if e.Fallback == nil {
+		return nil, err
+	}
+	if verbose {
+		log.Printf("report %s: %v. using %s.\n", report.URL, err, e.Fallback.Name())
+	}
+	trades, err := e.Fallback.Extract(ctx, content, report)
+	for i := range trades {
+		if trades[i].Ticker != "" && trades[i].TickerSource == "" {
+			trades[i].TickerSource = trade.TickerLLM
+		}
+	}
+	return trades, err
+}
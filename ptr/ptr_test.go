@@ -0,0 +1,252 @@
+package ptr
+
+import (
+	"clerk_trades/clerk"
+	"clerk_trades/trade"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// report returns the text layer of a report with rows in its transactions
+// table.
+func report(rows ...string) []string {
+	lines := []string{
+		"Filer Information",
+		"Name: Hon. Nancy Pelosi",
+		"Status: Member",
+		"State/District: CA11",
+		"Transactions",
+		"ID Owner Asset Transaction",
+		"Type",
+		"Date Notification",
+		"Date",
+		"Amount Cap.",
+		"Gains >",
+		"$200?",
+	}
+	lines = append(lines, rows...)
+	return append(lines, "* For the complete list of asset type abbreviations, please visit", "I CERTIFY that the statements I have made are true.")
+}
+
+func TestParse(t *testing.T) {
+	apple := trade.Trade{Name: "Nancy Pelosi", Owner: "Self", Asset: "Apple Inc. (AAPL) [ST]", Ticker: "AAPL",
+		TickerSource: trade.TickerFiling, Type: trade.Purchase, Date: "01/02/2025", Filed: "01/10/2025", Amount: "$1,001 - $15,000"}
+	with := func(f func(*trade.Trade)) trade.Trade {
+		t := apple
+		f(&t)
+		return t
+	}
+
+	tests := []struct {
+		name  string
+		lines []string
+		want  []trade.Trade
+	}{
+		{
+			"single row",
+			report("Apple Inc. (AAPL) [ST] P 01/02/2025 01/10/2025 $1,001 - $15,000 gfedc", "F S: New"),
+			[]trade.Trade{apple},
+		},
+		{
+			"wrapped row",
+			report(
+				"Apple Inc. (AAPL)",
+				"[ST] P 01/02/2025 01/10/2025 $1,001 -",
+				"$15,000",
+				"F S: New",
+			),
+			[]trade.Trade{apple},
+		},
+		{
+			"asset wrapped over lines",
+			report(
+				"Alphabet Inc. - Class A",
+				"Common Stock (GOOGL) [ST]",
+				"S (partial) 03/04/2025 03/20/2025 $15,001 - $50,000 gfedc",
+			),
+			[]trade.Trade{with(func(t *trade.Trade) {
+				t.Asset, t.Ticker = "Alphabet Inc. - Class A Common Stock (GOOGL) [ST]", "GOOGL"
+				t.Type, t.Date, t.Filed, t.Amount = trade.PartialSale, "03/04/2025", "03/20/2025", "$15,001 - $50,000"
+			})},
+		},
+		{
+			"transaction types",
+			report(
+				"Apple Inc. (AAPL) [ST] S (partial) 01/02/2025 01/10/2025 $1,001 - $15,000",
+				"Apple Inc. (AAPL) [ST] S 01/02/2025 01/10/2025 $1,001 - $15,000",
+				"Apple Inc. (AAPL) [ST] E 01/02/2025 01/10/2025 $1,001 - $15,000",
+			),
+			[]trade.Trade{
+				with(func(t *trade.Trade) { t.Type = trade.PartialSale }),
+				with(func(t *trade.Trade) { t.Type = trade.Sale }),
+				with(func(t *trade.Trade) { t.Type = trade.Exchange }),
+			},
+		},
+		{
+			"owners",
+			report(
+				"SP Apple Inc. (AAPL) [ST] P 01/02/2025 01/10/2025 $1,001 - $15,000",
+				"JT Apple Inc. (AAPL) [ST] P 01/02/2025 01/10/2025 $1,001 - $15,000",
+				"DC Apple Inc. (AAPL) [ST] P 01/02/2025 01/10/2025 $1,001 - $15,000",
+			),
+			[]trade.Trade{
+				with(func(t *trade.Trade) { t.Owner = "SP" }),
+				with(func(t *trade.Trade) { t.Owner = "JT" }),
+				with(func(t *trade.Trade) { t.Owner = "DC" }),
+			},
+		},
+		{
+			"open ended amounts",
+			report(
+				"SP Apple Inc. (AAPL) [ST] P 01/02/2025 01/10/2025 Over $50,000,000",
+				"SP Apple Inc. (AAPL) [ST] P 01/02/2025 01/10/2025 Spouse/DC Over $1,000,000",
+			),
+			[]trade.Trade{
+				with(func(t *trade.Trade) { t.Owner, t.Amount = "SP", "Over $50,000,000" }),
+				with(func(t *trade.Trade) { t.Owner, t.Amount = "SP", "Spouse/DC Over $1,000,000" }),
+			},
+		},
+		{
+			"capital gains checkboxes",
+			report(
+				"Apple Inc. (AAPL) [ST] S 01/02/2025 01/10/2025 $1,001 - $15,000 gfedcb",
+				"Apple Inc. (AAPL) [ST] S 01/02/2025 01/10/2025 $1,001 - $15,000 gfedc",
+				"Apple Inc. (AAPL) [ST] S 01/02/2025 01/10/2025 $1,001 - $15,000",
+				"gfedcb",
+				"Apple Inc. (AAPL) [ST] S 01/02/2025 01/10/2025 $1,001 - $15,000",
+				"gfedc",
+			),
+			[]trade.Trade{
+				with(func(t *trade.Trade) { t.Type, t.Cap = trade.Sale, true }),
+				with(func(t *trade.Trade) { t.Type = trade.Sale }),
+				with(func(t *trade.Trade) { t.Type, t.Cap = trade.Sale, true }),
+				with(func(t *trade.Trade) { t.Type = trade.Sale }),
+			},
+		},
+		{
+			"option descriptions",
+			report(
+				"SP NVIDIA Corporation (NVDA) [OP] P 01/14/2025 01/17/2025 $1,000,001 - $5,000,000",
+				"F S: New",
+				"D: Purchased 50 call options with a strike price of $80 and an expiration date of 1/16/26.",
+				"Apple Inc. (AAPL) [ST] P 01/02/2025 01/10/2025 $1,001 - $15,000",
+				"D : Second line",
+				"D: ignored, the description is already set",
+			),
+			[]trade.Trade{
+				with(func(t *trade.Trade) {
+					t.Owner, t.Asset, t.Ticker = "SP", "NVIDIA Corporation (NVDA) [OP]", "NVDA"
+					t.Date, t.Filed, t.Amount = "01/14/2025", "01/17/2025", "$1,000,001 - $5,000,000"
+					t.Description = "Purchased 50 call options with a strike price of $80 and an expiration date of 1/16/26."
+				}),
+				with(func(t *trade.Trade) { t.Description = "Second line" }),
+			},
+		},
+		{
+			"headers repeated on every page",
+			report(
+				"Apple Inc. (AAPL) [ST] P 01/02/2025 01/10/2025 $1,001 - $15,000",
+				"ID Owner Asset Transaction",
+				"Type",
+				"Date Notification",
+				"Date",
+				"Amount Cap.",
+				"Gains >",
+				"$200?",
+				"Apple Inc. (AAPL) [ST] S 01/02/2025 01/10/2025 $1,001 - $15,000",
+			),
+			[]trade.Trade{apple, with(func(t *trade.Trade) { t.Type = trade.Sale })},
+		},
+		{
+			"asset without ticker",
+			report("Treasury Bill [GS] P 01/02/2025 01/10/2025 $1,001 - $15,000"),
+			[]trade.Trade{with(func(t *trade.Trade) { t.Asset, t.Ticker, t.TickerSource = "Treasury Bill [GS]", "", "" })},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.lines)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("parsed %d trades, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i := range got {
+				if !reflect.DeepEqual(got[i], tt.want[i]) {
+					t.Errorf("trade %d:\n got %+v\nwant %+v", i+1, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseUnparsed(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+	}{
+		{"no text", nil},
+		{"no transactions table", []string{"Filer Information", "Name: Hon. Nancy Pelosi", "Schedule A: Assets"}},
+		{"empty transactions table", report()},
+		{"no filer name", report("Apple Inc. (AAPL) [ST] P 01/02/2025 01/10/2025 $1,001 - $15,000")[2:]},
+		{"row without transaction", report(strings.Split("Apple Inc. (AAPL) [ST] P 01/02/2025 and a lot of text that never becomes a row", " ")...)},
+		{"details in asset", report("Apple Inc. (AAPL) [ST] S O: Trust P 01/02/2025 01/10/2025 $1,001 - $15,000")},
+		{"annual report", []string{"Name: Hon. Nancy Pelosi", "$200?", "Schedule C: Earned Income", "Source Type Amount", "Salary $174,000"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.lines)
+			if !errors.Is(err, ErrUnparsed) {
+				t.Errorf("got %+v, %v, want %v", got, err, ErrUnparsed)
+			}
+		})
+	}
+}
+
+func TestReadHeader(t *testing.T) {
+	lines := append([]string{"Amendment to filing 20026590"},
+		report("Apple Inc. (AAPL) [ST] P 01/02/2025 01/10/2025 $1,001 - $15,000", "D: amended description")...)
+	lines = append(lines, "Digitally Signed: Hon. Nancy Pelosi , 01/17/2025")
+
+	want := Header{Amendment: true, Amends: "20026590", Signed: "01/17/2025"}
+	if h := readHeader(lines); h != want {
+		t.Errorf("read %+v, want %+v", h, want)
+	}
+	if h := readHeader(report("Apple Inc. (AAPL) [ST] P 01/02/2025 01/10/2025 $1,001 - $15,000 amended")); h != (Header{}) {
+		t.Errorf("read %+v from a report without header", h)
+	}
+}
+
+// scanReader stands in for an LLM that reads scanned reports.
+type scanReader struct{ calls int }
+
+func (s *scanReader) Name() string { return "scan" }
+
+func (s *scanReader) Extract(context.Context, []byte, clerk.Report) ([]trade.Trade, error) {
+	s.calls++
+	return []trade.Trade{{Name: "Nancy Pelosi", Ticker: "AAPL"}}, nil
+}
+
+func (s *scanReader) Close() error { return nil }
+
+func TestExtractorHeader(t *testing.T) {
+	fallback := &scanReader{}
+	e := New(fallback)
+	scan := clerk.NewReport("https://example.com/public_disc/ptr-pdfs/2025/20000001.pdf")
+	if _, ok := e.Header(scan); ok {
+		t.Error("header of a report that was not extracted is known")
+	}
+
+	trades, err := e.Extract(context.Background(), []byte("not a PDF"), scan)
+	if err != nil || len(trades) != 1 || fallback.calls != 1 || trades[0].TickerSource != trade.TickerLLM {
+		t.Fatalf("got %+v, %v from %d fallback calls", trades, err, fallback.calls)
+	}
+	// the report without text layer has an empty header, it is not read again
+	if h, ok := e.Header(scan); !ok || h != (Header{}) {
+		t.Errorf("header %+v, %v, want an empty one", h, ok)
+	}
+}
@@ -0,0 +1,122 @@
+package roster
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// LegislatorsURL is the list of current members of Congress kept by the
+// congress-legislators project. legislators-historical.json next to it lists
+// the former ones.
+const LegislatorsURL = "https://unitedstates.github.io/congress-legislators/legislators-current.json"
+
+// legislator is a member in the files of the congress-legislators project.
+type legislator struct {
+	ID struct {
+		Bioguide string `json:"bioguide"`
+	} `json:"id"`
+	Name struct {
+		First        string `json:"first"`
+		Last         string `json:"last"`
+		Nickname     string `json:"nickname"`
+		OfficialFull string `json:"official_full"`
+	} `json:"name"`
+	Terms []struct {
+		Type     string `json:"type"` // rep or sen
+		State    string `json:"state"`
+		District int    `json:"district"`
+		Party    string `json:"party"`
+	} `json:"terms"`
+}
+
+var parties = map[string]string{
+	"Democrat":    "D",
+	"Republican":  "R",
+	"Independent": "I",
+	"Libertarian": "L",
+}
+
+// ReadLegislators reads the members of a file of the congress-legislators
+// project, like the one at LegislatorsURL. Members are described by their
+// last term. Their official name is kept as name, and their first and last
+// name, and their nickname, as aliases.
+func ReadLegislators(file string) ([]Member, error) {
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("legislators file %s not found", file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read legislators %s: %w", file, err)
+	}
+	var legislators []legislator
+	if err := json.Unmarshal(data, &legislators); err != nil {
+		return nil, fmt.Errorf("failed to read legislators %s: %w", file, err)
+	}
+
+	members := make([]Member, 0, len(legislators))
+	for _, l := range legislators {
+		if l.ID.Bioguide == "" || len(l.Terms) == 0 {
+			continue
+		}
+		term := l.Terms[len(l.Terms)-1]
+
+		short := strings.TrimSpace(l.Name.First + " " + l.Name.Last)
+		m := Member{
+			ID:    l.ID.Bioguide,
+			Name:  l.Name.OfficialFull,
+			Party: parties[term.Party],
+			State: term.State,
+		}
+		if m.Name == "" {
+			m.Name = short
+		}
+		if m.Party == "" && term.Party != "" {
+			m.Party = term.Party[:1]
+		}
+		if term.Type == "sen" {
+			m.Chamber = "Senate"
+		} else {
+			m.Chamber = "House"
+			m.District = term.District
+		}
+		for _, alias := range []string{short, strings.TrimSpace(l.Name.Nickname + " " + l.Name.Last)} {
+			if alias != m.Name && alias != l.Name.Last && !slices.Contains(m.Aliases, alias) {
+				m.Aliases = append(m.Aliases, alias)
+			}
+		}
+		members = append(members, m)
+	}
+	if len(members) == 0 {
+		return nil, fmt.Errorf("no legislators in %s", file)
+	}
+	return members, nil
+}
+
+// Merge returns members with the members of update added. Members of update
+// replace the members with the same ID, but keep their aliases.
+func Merge(members, update []Member) []Member {
+	merged := slices.Clone(members)
+	byID := make(map[string]int, len(merged))
+	for i, m := range merged {
+		byID[strings.ToUpper(m.ID)] = i
+	}
+	for _, m := range update {
+		i, ok := byID[strings.ToUpper(m.ID)]
+		if !ok {
+			byID[strings.ToUpper(m.ID)] = len(merged)
+			merged = append(merged, m)
+			continue
+		}
+		for _, alias := range merged[i].Aliases {
+			if alias != m.Name && !slices.Contains(m.Aliases, alias) {
+				m.Aliases = append(m.Aliases, alias)
+			}
+		}
+		merged[i] = m
+	}
+	return merged
+}
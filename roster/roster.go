@@ -0,0 +1,152 @@
+package roster
+
+import (
+	"clerk_trades/names"
+	"clerk_trades/utils"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// FILE_ROSTER holds members that are added to the bundled roster or replace
+// members of it with the same ID. 'members import' creates it from the
+// congress-legislators data.
+const FILE_ROSTER = "roster.json"
+
+//go:embed members.json
+var bundled []byte
+
+// Member is a member of Congress. ID is the Bioguide ID, which stays the
+// same across terms and chambers.
+type Member struct {
+	ID       string   `json:"ID"`   // e.g. P000197
+	Name     string   `json:"Name"` // "First Last"
+	Party    string   `json:"Party"`
+	State    string   `json:"State"`              // two letter code
+	District int      `json:"District,omitempty"` // 0 for senators and at-large seats
+	Chamber  string   `json:"Chamber"`            // House or Senate
+	Aliases  []string `json:"Aliases,omitempty"`  // other names the member files under
+}
+
+// Office returns the office of m the way the Clerk lists it, e.g. "CA11".
+func (m Member) Office() string {
+	if m.Chamber == "Senate" {
+		return m.State
+	}
+	return fmt.Sprintf("%s%02d", m.State, m.District)
+}
+
+// Label returns the name of m with party and office, e.g.
+// "Nancy Pelosi (D-CA11)".
+func (m Member) Label() string {
+	return fmt.Sprintf("%s (%s-%s)", m.Name, m.Party, m.Office())
+}
+
+// Roster resolves member names to canonical members.
+type Roster struct {
+	members []Member
+	byID    map[string]int
+}
+
+// Load returns the bundled roster with the members of file added. Later
+// members replace earlier ones with the same ID. A missing file is not an
+// error.
+func Load(file string) (*Roster, error) {
+	var members []Member
+	if err := json.Unmarshal(bundled, &members); err != nil {
+		return nil, fmt.Errorf("failed to read bundled roster: %v", err)
+	}
+
+	if _, err := os.Stat(file); err == nil {
+		extra, err := utils.ReadJSON[[]Member](file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read roster %s: %w", file, err)
+		}
+		members = append(members, extra...)
+	}
+
+	r := &Roster{byID: make(map[string]int)}
+	for _, m := range members {
+		m.ID = strings.ToUpper(strings.TrimSpace(m.ID))
+		if m.ID == "" || m.Name == "" {
+			return nil, fmt.Errorf("roster member %q needs an ID and a name", m.Name)
+		}
+		if i, ok := r.byID[m.ID]; ok {
+			r.members[i] = m
+			continue
+		}
+		r.byID[m.ID] = len(r.members)
+		r.members = append(r.members, m)
+	}
+	return r, nil
+}
+
+// Member returns the member with Bioguide ID id.
+func (r *Roster) Member(id string) (Member, bool) {
+	i, ok := r.byID[strings.ToUpper(id)]
+	if !ok {
+		return Member{}, false
+	}
+	return r.members[i], true
+}
+
+// Members returns all members sorted by name.
+func (r *Roster) Members() []Member {
+	members := append([]Member(nil), r.members...)
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Name < members[j].Name
+	})
+	return members
+}
+
+// Resolve returns the member that filed as name, e.g. "Pelosi, Nancy" or
+// "Hon. Nancy Pelosi". name may also be a Bioguide ID. A member matches when
+// all words of its name or of an alias appear in name; office, like "CA11",
+// decides between several matches. Ambiguous names resolve to no member.
+func (r *Roster) Resolve(name, office string) (Member, bool) {
+	if m, ok := r.Member(strings.TrimSpace(name)); ok {
+		return m, true
+	}
+
+	var found []Member
+	for _, m := range r.members {
+		if r.matches(m, name) {
+			found = append(found, m)
+		}
+	}
+
+	if len(found) > 1 && office != "" {
+		var inOffice []Member
+		for _, m := range found {
+			if strings.EqualFold(m.Office(), office) {
+				inOffice = append(inOffice, m)
+			}
+		}
+		found = inOffice
+	}
+	if len(found) != 1 {
+		return Member{}, false
+	}
+	return found[0], true
+}
+
+// ID returns the ID of the member that filed as name, or "".
+func (r *Roster) ID(name, office string) string {
+	m, _ := r.Resolve(name, office)
+	return m.ID
+}
+
+func (r *Roster) matches(m Member, name string) bool {
+	if names.Match(m.Name, name) {
+		return true
+	}
+	for _, alias := range m.Aliases {
+		if names.Match(alias, name) {
+			return true
+		}
+	}
+	return false
+}
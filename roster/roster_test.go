@@ -0,0 +1,122 @@
+package roster
+
+import (
+	"clerk_trades/utils"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const legislators = "testdata/legislators-current.json"
+
+func TestReadLegislators(t *testing.T) {
+	members, err := ReadLegislators(legislators)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Member{
+		{ID: "P000197", Name: "Nancy Pelosi", Party: "D", State: "CA", District: 11, Chamber: "House"},
+		{ID: "K000389", Name: "Ro Khanna", Party: "D", State: "CA", District: 17, Chamber: "House", Aliases: []string{"Rohit Khanna"}},
+		{ID: "M001157", Name: "Michael T. McCaul", Party: "R", State: "TX", District: 10, Chamber: "House", Aliases: []string{"Michael McCaul"}},
+		{ID: "J000295", Name: "David P. Joyce", Party: "R", State: "OH", District: 14, Chamber: "House", Aliases: []string{"David Joyce"}},
+		{ID: "J000289", Name: "Jim Jordan", Party: "R", State: "OH", District: 4, Chamber: "House"},
+		{ID: "S000033", Name: "Bernard Sanders", Party: "I", State: "VT", Chamber: "Senate", Aliases: []string{"Bernie Sanders"}},
+		{ID: "N000147", Name: "Eleanor Holmes Norton", Party: "D", State: "DC", Chamber: "House", Aliases: []string{"Eleanor Norton"}},
+	}
+	if !reflect.DeepEqual(members, want) {
+		t.Errorf("read\n%+v\nwant\n%+v", members, want)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	members := []Member{
+		{ID: "P000197", Name: "Nancy Pelosi", State: "CA", District: 12, Aliases: []string{"Nancy D'Alesandro Pelosi"}},
+		{ID: "S001234", Name: "Jane Smith"},
+	}
+	update := []Member{
+		{ID: "p000197", Name: "Nancy Pelosi", State: "CA", District: 11},
+		{ID: "K000389", Name: "Ro Khanna"},
+	}
+	want := []Member{
+		{ID: "p000197", Name: "Nancy Pelosi", State: "CA", District: 11, Aliases: []string{"Nancy D'Alesandro Pelosi"}},
+		{ID: "S001234", Name: "Jane Smith"},
+		{ID: "K000389", Name: "Ro Khanna"},
+	}
+	if got := Merge(members, update); !reflect.DeepEqual(got, want) {
+		t.Errorf("merged\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), FILE_ROSTER)
+	r, err := Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Members()) == 0 || r.ID("Pelosi, Nancy", "CA11") != "P000197" {
+		t.Errorf("bundled roster was not loaded")
+	}
+	if _, err := os.Stat(file); err == nil {
+		t.Errorf("loading created %s", file)
+	}
+
+	// members of the file replace the bundled ones
+	err = utils.WriteJSON(file, []Member{{ID: "P000197", Name: "Nancy Pelosi", State: "CA", District: 12, Chamber: "House"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, err = Load(file); err != nil {
+		t.Fatal(err)
+	}
+	if m, _ := r.Member("P000197"); m.Office() != "CA12" {
+		t.Errorf("member of %s did not replace the bundled one: %+v", FILE_ROSTER, m)
+	}
+}
+
+func TestReadLegislatorsMissing(t *testing.T) {
+	for _, file := range []string{"typo.json", "missing/legislators.json"} {
+		file = filepath.Join(t.TempDir(), file)
+		_, err := ReadLegislators(file)
+		if err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Errorf("reading %s returned %v, want not found", file, err)
+		}
+		if _, err := os.Stat(file); err == nil {
+			t.Errorf("reading created %s", file)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	members, err := ReadLegislators(legislators)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), FILE_ROSTER)
+	if err := utils.WriteJSON(file, members); err != nil {
+		t.Fatal(err)
+	}
+	r, err := Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, office, want string
+	}{
+		{"Pelosi, Nancy", "CA11", "P000197"},
+		{"Hon. Nancy Pelosi", "", "P000197"},
+		{"McCaul, Hon.. Michael T.", "TX10", "M001157"},
+		{"Michael McCaul", "", "M001157"},
+		{"Khanna, Ro", "CA17", "K000389"},
+		{"Rohit Khanna", "", "K000389"},
+		{"p000197", "", "P000197"},
+		{"Smith, Jane", "", ""},
+	}
+	for _, tt := range tests {
+		if got := r.ID(tt.name, tt.office); got != tt.want {
+			t.Errorf("%q (%s) resolves to %q, want %q", tt.name, tt.office, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package security
+
+import (
+	"clerk_trades/names"
+	"clerk_trades/trade"
+	"cmp"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	// FILE_SECURITIES is the security master, a CSV file with the columns
+	// ticker, cusip, name and class, e.g. "AAPL,037833100,Apple Inc.,ST".
+	FILE_SECURITIES = "securities.csv"
+
+	// FILE_OVERRIDES maps asset descriptions to tickers, a CSV file with the
+	// columns asset and ticker, and optionally cusip and class. Overrides
+	// win over the filing, the model and the security master.
+	FILE_OVERRIDES = "ticker_overrides.csv"
+)
+
+// minScore is the share of words an asset description and the name of a
+// security must have in common to match.
+const minScore = 0.8
+
+// Security is a listed security of the security master.
+type Security struct {
+	Ticker string
+	CUSIP  string
+	Name   string
+	Class  string // asset type code, e.g. ST or EF, see trade.AssetTypes
+}
+
+// Master resolves asset descriptions of reports to securities.
+type Master struct {
+	securities []Security
+	words      [][]string // significant words of every security name
+	byTicker   map[string]int
+	byCUSIP    map[string]int
+	overrides  map[string]Security // by normalized asset description
+}
+
+var (
+	cusipRe   = regexp.MustCompile(`\b[0-9]{3}[0-9A-Z]{5}[0-9]\b`)
+	bracketRe = regexp.MustCompile(`\([^)]*\)|\[[^\]]*\]`)
+
+	// stopwords say nothing about which security is meant.
+	stopwords = map[string]bool{
+		"inc": true, "incorporated": true, "corp": true, "corporation": true,
+		"co": true, "company": true, "ltd": true, "limited": true, "plc": true,
+		"llc": true, "lp": true, "sa": true, "nv": true, "ag": true, "the": true,
+		"class": true, "cl": true, "common": true, "stock": true, "shares": true,
+		"share": true, "ordinary": true, "adr": true, "ads": true, "new": true,
+	}
+)
+
+// Load reads the security master and the overrides from dir. Missing files
+// leave the master empty.
+func Load(dir string) (*Master, error) {
+	m := &Master{
+		byTicker:  make(map[string]int),
+		byCUSIP:   make(map[string]int),
+		overrides: make(map[string]Security),
+	}
+
+	rows, err := readCSV(filepath.Join(dir, FILE_SECURITIES), "ticker", "cusip", "name", "class")
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		s := Security{
+			Ticker: strings.ToUpper(row["ticker"]),
+			CUSIP:  strings.ToUpper(row["cusip"]),
+			Name:   row["name"],
+			Class:  strings.ToUpper(row["class"]),
+		}
+		if s.Ticker == "" || s.Name == "" {
+			continue
+		}
+		i := len(m.securities)
+		m.securities = append(m.securities, s)
+		m.words = append(m.words, Words(s.Name))
+		m.byTicker[s.Ticker] = i
+		if s.CUSIP != "" {
+			m.byCUSIP[s.CUSIP] = i
+		}
+	}
+
+	rows, err = readCSV(filepath.Join(dir, FILE_OVERRIDES), "asset", "ticker")
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		key := normalize(row["asset"])
+		if key == "" || row["ticker"] == "" {
+			continue
+		}
+		s := Security{
+			Ticker: strings.ToUpper(row["ticker"]),
+			CUSIP:  strings.ToUpper(row["cusip"]),
+			Class:  strings.ToUpper(row["class"]),
+		}
+		if known, ok := m.Ticker(s.Ticker); ok {
+			s.Name = known.Name
+			s.CUSIP = cmp.Or(s.CUSIP, known.CUSIP)
+			s.Class = cmp.Or(s.Class, known.Class)
+		}
+		m.overrides[key] = s
+	}
+	return m, nil
+}
+
+// Len returns the number of securities in the master.
+func (m *Master) Len() int {
+	return len(m.securities)
+}
+
+// Ticker returns the security with ticker.
+func (m *Master) Ticker(ticker string) (Security, bool) {
+	i, ok := m.byTicker[strings.ToUpper(strings.TrimSpace(ticker))]
+	if !ok {
+		return Security{}, false
+	}
+	return m.securities[i], true
+}
+
+// Override returns the override of asset description asset.
+func (m *Master) Override(asset string) (Security, bool) {
+	s, ok := m.overrides[normalize(asset)]
+	return s, ok
+}
+
+// Match returns the security that asset description asset names: the one
+// with a CUSIP given in asset, or else the one whose name has the most words
+// in common with asset. Weak and tied matches return no security.
+func (m *Master) Match(asset string) (Security, bool) {
+	for _, c := range cusipRe.FindAllString(strings.ToUpper(asset), -1) {
+		if i, ok := m.byCUSIP[c]; ok {
+			return m.securities[i], true
+		}
+	}
+
+	words := Words(asset)
+	if len(words) == 0 {
+		return Security{}, false
+	}
+	best, bestScore, tied := -1, 0.0, false
+	for i, w := range m.words {
+		score := dice(words, w)
+		switch {
+		case score > bestScore:
+			best, bestScore, tied = i, score, false
+		case score == bestScore && score > 0:
+			tied = true
+		}
+	}
+	if best < 0 || bestScore < minScore || tied {
+		return Security{}, false
+	}
+	return m.securities[best], true
+}
+
+// Apply sets the ticker of t from the overrides and the security master and
+// records where it came from:
+//   - an override always wins,
+//   - a missing ticker is taken from a confident match,
+//   - a ticker of the model is replaced by a confident match that disagrees,
+//     as models tend to guess tickers,
+//   - a ticker printed in the filing, or of unknown source, is kept.
+//
+// CUSIP and the asset type are filled in from the security if missing.
+func (m *Master) Apply(t *trade.Trade) {
+	if m == nil {
+		return
+	}
+
+	if s, ok := m.Override(t.Asset); ok {
+		t.Ticker, t.TickerSource = s.Ticker, trade.TickerOverride
+		m.fill(t, s)
+		return
+	}
+
+	s, ok := m.Match(t.Asset)
+	switch {
+	case !ok:
+		if known, ok := m.Ticker(t.Ticker); ok {
+			m.fill(t, known)
+		}
+	case t.Ticker == "" || (t.TickerSource == trade.TickerLLM && !strings.EqualFold(t.Ticker, s.Ticker)):
+		t.Ticker, t.TickerSource = s.Ticker, trade.TickerResolver
+		m.fill(t, s)
+	case strings.EqualFold(t.Ticker, s.Ticker):
+		m.fill(t, s)
+	default:
+		if known, ok := m.Ticker(t.Ticker); ok {
+			m.fill(t, known)
+		}
+	}
+}
+
+func (m *Master) fill(t *trade.Trade, s Security) {
+	if t.CUSIP == "" {
+		t.CUSIP = s.CUSIP
+	}
+	if t.AssetType == "" {
+		t.AssetType = s.Class
+	}
+}
+
+// Words returns the significant words of an asset description or security
+// name: folded, without the parts in brackets, after " - ", and without
+// stopwords like "Inc." or "Class A".
+func Words(s string) []string {
+	s = bracketRe.ReplaceAllString(s, " ")
+	if before, _, ok := strings.Cut(s, " - "); ok {
+		s = before
+	}
+
+	var words []string
+	for _, w := range names.Words(s) {
+		if len([]rune(w)) > 1 && !stopwords[w] {
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+func normalize(asset string) string {
+	return strings.Join(names.Words(asset), " ")
+}
+
+// dice returns the Dice coefficient of the word sets a and b, 1 if they are
+// equal and 0 if they have no word in common.
+func dice(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	in := make(map[string]bool, len(a))
+	for _, w := range a {
+		in[w] = true
+	}
+	common := 0
+	seen := make(map[string]bool, len(b))
+	for _, w := range b {
+		if in[w] && !seen[w] {
+			common++
+		}
+		seen[w] = true
+	}
+	return 2 * float64(common) / float64(len(in)+len(seen))
+}
+
+// readCSV reads the rows of a CSV file with a header naming at least the
+// columns required. A missing file has no rows.
+func readCSV(file string, required ...string) ([]map[string]string, error) {
+	f, err := os.Open(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	r.Comment = '#'
+
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", file, err)
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+	for _, col := range required {
+		if !slices.Contains(header, col) {
+			return nil, fmt.Errorf("%s has no %q column", file, col)
+		}
+	}
+
+	var rows []map[string]string
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", file, err)
+		}
+		row := make(map[string]string, len(header))
+		for i, v := range record {
+			if i < len(header) {
+				row[header[i]] = strings.TrimSpace(v)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
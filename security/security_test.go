@@ -0,0 +1,157 @@
+package security
+
+import (
+	"clerk_trades/trade"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const securities = `ticker,cusip,name,class
+AAPL,037833100,Apple Inc.,ST
+MSFT,594918104,Microsoft Corporation,
+GOOGL,02079K305,Alphabet Inc. Class A,ST
+GOOG,02079K107,Alphabet Inc. Class C,ST
+TXN,882508104,Texas Instruments Incorporated,ST
+META,30303M102,Meta Platforms Inc. Class A,ST
+`
+
+const overrides = `asset,ticker
+Facebook Inc. Common Stock,META
+`
+
+// testMaster loads a master with a few securities and one override.
+func testMaster(t *testing.T) *Master {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, FILE_SECURITIES), []byte(securities), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, FILE_OVERRIDES), []byte(overrides), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestMatch(t *testing.T) {
+	m := testMaster(t)
+
+	tests := []struct {
+		name   string
+		asset  string
+		ticker string
+	}{
+		{"name", "Apple Inc. (AAPL) [ST]", "AAPL"},
+		{"name with details", "Apple Inc. - Common Stock", "AAPL"},
+		{"word order and case", "CORPORATION MICROSOFT", "MSFT"},
+		{"CUSIP", "Common stock, CUSIP 037833100", "AAPL"},
+		{"CUSIP over name", "Microsoft Corp 037833100", "AAPL"},
+		{"unknown CUSIP", "Microsoft Corp 999999999", ""},
+		{"score of 0.8", "Texas Instruments Semiconductor", "TXN"},
+		{"score below 0.8", "Texas Instruments Semiconductor Devices", ""},
+		{"weak", "Microsoft Gaming Holdings", ""},
+		{"tied", "Alphabet Inc.", ""},
+		{"class is no word", "Alphabet Inc. Class A", ""},
+		{"no words", "Inc. (XYZ) [ST]", ""},
+		{"unknown", "Treasury Bill", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, ok := m.Match(tt.asset)
+			if ok != (tt.ticker != "") || s.Ticker != tt.ticker {
+				t.Errorf("%q matches %q, %v, want %q", tt.asset, s.Ticker, ok, tt.ticker)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	m := testMaster(t)
+
+	tests := []struct {
+		name  string
+		trade trade.Trade
+		want  trade.Trade
+	}{
+		{
+			"override wins over the filing",
+			trade.Trade{Asset: "Facebook Inc. Common Stock", Ticker: "FB", TickerSource: trade.TickerFiling},
+			trade.Trade{Asset: "Facebook Inc. Common Stock", Ticker: "META", TickerSource: trade.TickerOverride, CUSIP: "30303M102", AssetType: "ST"},
+		},
+		{
+			"filing wins over a match",
+			trade.Trade{Asset: "Apple Inc.", Ticker: "APPL", TickerSource: trade.TickerFiling},
+			trade.Trade{Asset: "Apple Inc.", Ticker: "APPL", TickerSource: trade.TickerFiling},
+		},
+		{
+			"ticker of unknown source is kept",
+			trade.Trade{Asset: "Apple Inc.", Ticker: "APPL"},
+			trade.Trade{Asset: "Apple Inc.", Ticker: "APPL"},
+		},
+		{
+			"match wins over the model",
+			trade.Trade{Asset: "Apple Inc.", Ticker: "APPL", TickerSource: trade.TickerLLM},
+			trade.Trade{Asset: "Apple Inc.", Ticker: "AAPL", TickerSource: trade.TickerResolver, CUSIP: "037833100", AssetType: "ST"},
+		},
+		{
+			"model agrees with the match",
+			trade.Trade{Asset: "Apple Inc.", Ticker: "aapl", TickerSource: trade.TickerLLM},
+			trade.Trade{Asset: "Apple Inc.", Ticker: "aapl", TickerSource: trade.TickerLLM, CUSIP: "037833100", AssetType: "ST"},
+		},
+		{
+			"missing ticker is resolved",
+			trade.Trade{Asset: "Microsoft Corp"},
+			trade.Trade{Asset: "Microsoft Corp", Ticker: "MSFT", TickerSource: trade.TickerResolver, CUSIP: "594918104"},
+		},
+		{
+			"weak match leaves the model",
+			trade.Trade{Asset: "Alphabet Inc.", Ticker: "GOOGL", TickerSource: trade.TickerLLM},
+			trade.Trade{Asset: "Alphabet Inc.", Ticker: "GOOGL", TickerSource: trade.TickerLLM, CUSIP: "02079K305", AssetType: "ST"},
+		},
+		{
+			"asset type of the filing is kept",
+			trade.Trade{Asset: "Apple Inc.", AssetType: "OP"},
+			trade.Trade{Asset: "Apple Inc.", Ticker: "AAPL", TickerSource: trade.TickerResolver, CUSIP: "037833100", AssetType: "OP"},
+		},
+		{
+			"no match",
+			trade.Trade{Asset: "Treasury Bill"},
+			trade.Trade{Asset: "Treasury Bill"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.trade
+			m.Apply(&got)
+			if got.Ticker != tt.want.Ticker || got.TickerSource != tt.want.TickerSource ||
+				got.CUSIP != tt.want.CUSIP || got.AssetType != tt.want.AssetType {
+				t.Errorf("applied\n got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+
+	var none *Master
+	none.Apply(&trade.Trade{Asset: "Apple Inc."})
+}
+
+func TestLoadMissing(t *testing.T) {
+	m, err := Load(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Len() != 0 {
+		t.Errorf("empty master has %d securities", m.Len())
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, FILE_SECURITIES), []byte("symbol,name\nAAPL,Apple Inc.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(dir); err == nil {
+		t.Error("master without ticker column loaded")
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"clerk_trades/store"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// serve answers read-only JSON requests for the stored reports and trades
+// on addr until it fails.
+func serve(addr string) error {
+	// the store is only read, and changes of other commands are served
+	// as soon as they are committed
+	s, err := store.OpenReadOnly(storagePath(store.FILE_STORE))
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /reports", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.Entries(store.EntryFilter{}))
+	})
+	mux.HandleFunc("GET /reports/{docid}", func(w http.ResponseWriter, r *http.Request) {
+		e, ok := s.Report(r.PathValue("docid"))
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "report not found"})
+			return
+		}
+		writeJSON(w, http.StatusOK, struct {
+			store.Entry
+			Trades []store.Trade
+		}{e, s.Trades(e.Report)})
+	})
+	mux.HandleFunc("GET /trades", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		f := store.TradeFilter{
+			Accepted: true,
+			MemberID: strings.ToUpper(q.Get("member")),
+			Ticker:   q.Get("ticker"),
+			Text:     q.Get("q"),
+		}
+		for _, p := range []struct {
+			param string
+			date  *time.Time
+		}{{"since", &f.From}, {"until", &f.To}} {
+			if v := q.Get(p.param); v != "" {
+				d, err := time.Parse(time.DateOnly, v)
+				if err != nil {
+					writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid " + p.param + " date"})
+					return
+				}
+				*p.date = d
+			}
+		}
+		writeJSON(w, http.StatusOK, s.Query(f))
+	})
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Printf("serving reports and trades on http://%s.\n", addr)
+	return server.ListenAndServe()
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil && verbose {
+		log.Println("error writing response:", err)
+	}
+}
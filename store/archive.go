@@ -0,0 +1,70 @@
+package store
+
+import (
+	"clerk_trades/archive"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Manifest returns the manifest of the archive, which is kept in the store
+// so every command sees the filings the others archived.
+func (s *Store) Manifest() archive.Manifest {
+	return manifest{s}
+}
+
+type manifest struct {
+	s *Store
+}
+
+func (m manifest) Item(docID string) (archive.Item, bool) {
+	var item archive.Item
+	var data string
+	err := m.s.db.QueryRow(`SELECT item FROM archived WHERE doc_id = ?`, docID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return item, false
+	}
+	if err == nil {
+		err = json.Unmarshal([]byte(data), &item)
+	}
+	if err != nil {
+		logError(fmt.Errorf("failed to read archived report %s: %w", docID, err))
+		return item, false
+	}
+	return item, true
+}
+
+func (m manifest) SetItem(item archive.Item) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	return m.s.update(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`INSERT INTO archived (doc_id, item) VALUES (?, ?)
+			ON CONFLICT (doc_id) DO UPDATE SET item = excluded.item`, item.DocID, string(data))
+		return err
+	})
+}
+
+func (m manifest) Items() ([]archive.Item, error) {
+	rows, err := m.s.db.Query(`SELECT item FROM archived ORDER BY doc_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read store: %w", err)
+	}
+	defer rows.Close()
+
+	var items []archive.Item
+	for rows.Next() {
+		var data string
+		var item archive.Item
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(data), &item); err != nil {
+			return nil, fmt.Errorf("failed to read archived report: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
@@ -0,0 +1,1106 @@
+package store
+
+import (
+	"clerk_trades/clerk"
+	"clerk_trades/names"
+	"clerk_trades/trade"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// FILE_STORE is the SQLite database of the store.
+const FILE_STORE = "store.db"
+
+// State is the processing step a report has reached.
+type State string
+
+const (
+	Discovered State = "discovered"
+	Downloaded State = "downloaded"
+	Extracted  State = "extracted"
+	Notified   State = "notified"
+	Failed     State = "failed"
+
+	// Skipped reports are wanted by no watchlist. They are processed once
+	// one wants them.
+	Skipped State = "skipped"
+)
+
+const (
+	// MaxAttempts is how often a failing report is tried before it is left
+	// for manual inspection.
+	MaxAttempts = 5
+	retryDelay  = time.Hour
+	maxDelay    = 24 * time.Hour
+)
+
+// Entry tracks a report through the processing states.
+type Entry struct {
+	clerk.Report
+	State     State     `json:"State"`
+	FailedIn  State     `json:"FailedIn,omitempty"` // state the report was in when it failed
+	Attempts  int       `json:"Attempts,omitempty"`
+	LastError string    `json:"LastError,omitempty"`
+	NextRetry time.Time `json:"NextRetry,omitempty"`
+	Updated   time.Time `json:"Updated"`
+}
+
+// Due reports whether the entry should be processed at now.
+func (e Entry) Due(now time.Time) bool {
+	switch e.State {
+	case Notified, Skipped:
+		return false
+	case Failed:
+		return e.Attempts < MaxAttempts && !now.Before(e.NextRetry)
+	}
+	return true
+}
+
+// Status is the review status of a stored trade.
+type Status string
+
+const (
+	Accepted Status = "accepted" // passed validation
+	Review   Status = "review"   // has validation issues, waits for review
+	Approved Status = "approved" // accepted in review
+	Rejected Status = "rejected" // rejected in review
+)
+
+// Trade is an extracted trade. Its ReportID references the report it was
+// extracted from, which always exists in the store.
+type Trade struct {
+	ID       int    `json:"ID"`
+	Status   Status `json:"Status"`
+	Restates int    `json:"Restates,omitempty"` // ID of the trade of an earlier report this one repeats
+
+	// Superseded is the DocID of the amendment that corrects or drops the
+	// trade, as the amendment does not restate it.
+	Superseded string `json:"Superseded,omitempty"`
+	trade.Trade
+}
+
+// Accepted reports whether the trade may be listed and notified. Trades
+// stored before they were validated have no status and are accepted. Trades
+// that restate an earlier one were listed and notified with it, and
+// superseded trades are replaced by the trades of their amendment.
+func (t Trade) Accepted() bool {
+	return t.Status != Review && t.Status != Rejected && t.Restates == 0 && t.Superseded == ""
+}
+
+// HasTrades reports whether the trades of the entry were extracted and stored.
+func (e Entry) HasTrades() bool {
+	return e.State == Extracted || e.State == Notified
+}
+
+// Store persists every discovered report together with its processing
+// state, and the trades extracted from them, in a SQLite database. Every
+// change is committed before it returns, so a crash never loses a report or
+// the step it reached, and commands running at the same time, like watch
+// and review, see each other's changes instead of overwriting them.
+type Store struct {
+	db       *sql.DB
+	readOnly bool
+	resolve  func(name, office string) string
+}
+
+// The details of reports and trades are kept as JSON. What they are looked
+// up and filtered by is kept in columns of its own, which take precedence
+// over the JSON.
+const schema = `
+CREATE TABLE IF NOT EXISTS reports (
+	doc_id     TEXT PRIMARY KEY,
+	url        TEXT NOT NULL UNIQUE,
+	member_id  TEXT NOT NULL DEFAULT '',
+	year       INTEGER NOT NULL DEFAULT 0,
+	amends     TEXT NOT NULL DEFAULT '', -- DocID of the amended report
+	report     TEXT NOT NULL, -- clerk.Report as JSON
+	state      TEXT NOT NULL,
+	failed_in  TEXT NOT NULL DEFAULT '',
+	attempts   INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL DEFAULT '',
+	next_retry TEXT NOT NULL DEFAULT '',
+	updated    TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS reports_state ON reports(state);
+CREATE INDEX IF NOT EXISTS reports_member ON reports(member_id);
+CREATE INDEX IF NOT EXISTS reports_amends ON reports(amends);
+CREATE TABLE IF NOT EXISTS trades (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	report_id   TEXT NOT NULL REFERENCES reports(doc_id) ON DELETE CASCADE,
+	status      TEXT NOT NULL,
+	restates    INTEGER REFERENCES trades(id) ON DELETE SET NULL,
+	superseded  TEXT REFERENCES reports(doc_id) ON DELETE SET NULL, -- DocID of the amendment
+	member_id   TEXT NOT NULL DEFAULT '',
+	ticker      TEXT NOT NULL DEFAULT '' COLLATE NOCASE,
+	trade_date  TEXT NOT NULL DEFAULT '', -- YYYY-MM-DD
+	disclosed   TEXT NOT NULL DEFAULT '', -- YYYY-MM-DD
+	restate_key TEXT NOT NULL DEFAULT '', -- see restateKey
+	trade       TEXT NOT NULL -- trade.Trade as JSON
+);
+CREATE INDEX IF NOT EXISTS trades_report ON trades(report_id);
+CREATE INDEX IF NOT EXISTS trades_status ON trades(status);
+CREATE INDEX IF NOT EXISTS trades_restates ON trades(restates);
+CREATE INDEX IF NOT EXISTS trades_superseded ON trades(superseded);
+CREATE INDEX IF NOT EXISTS trades_member ON trades(member_id);
+CREATE INDEX IF NOT EXISTS trades_ticker ON trades(ticker);
+CREATE INDEX IF NOT EXISTS trades_date ON trades(trade_date);
+CREATE INDEX IF NOT EXISTS trades_disclosed ON trades(disclosed);
+CREATE INDEX IF NOT EXISTS trades_key ON trades(restate_key);
+CREATE TABLE IF NOT EXISTS lists (
+	name    TEXT PRIMARY KEY,
+	started TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS sent (
+	list     TEXT NOT NULL,
+	trade_id INTEGER NOT NULL REFERENCES trades(id) ON DELETE CASCADE,
+	PRIMARY KEY (list, trade_id)
+);
+CREATE INDEX IF NOT EXISTS sent_trade ON sent(trade_id);
+CREATE TABLE IF NOT EXISTS archived (
+	doc_id TEXT PRIMARY KEY,
+	item   TEXT NOT NULL -- archive.Item as JSON
+);
+`
+
+// ErrReadOnly is returned by changes to a store opened with OpenReadOnly.
+var ErrReadOnly = errors.New("store is read-only")
+
+// Open opens the store in file, or creates an empty one if it does not
+// exist.
+func Open(file string) (*Store, error) {
+	db, err := sql.Open("sqlite", dsn(file, "_txlock=immediate"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store %s: %w", file, err)
+	}
+	db.SetMaxOpenConns(1)
+	s := &Store{db: db}
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open store %s: %w", file, err)
+	}
+	return s, nil
+}
+
+// OpenReadOnly opens the existing store in file without ever writing to it.
+func OpenReadOnly(file string) (*Store, error) {
+	if _, err := os.Stat(file); err != nil {
+		return nil, fmt.Errorf("failed to open store %s: %w", file, err)
+	}
+	db, err := sql.Open("sqlite", dsn(file, "mode=ro"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store %s: %w", file, err)
+	}
+	db.SetMaxOpenConns(1)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open store %s: %w", file, err)
+	}
+	return &Store{db: db, readOnly: true}, nil
+}
+
+func dsn(file, params string) string {
+	return "file:" + (&url.URL{Path: file}).EscapedPath() + "?" + params +
+		"&_pragma=foreign_keys(1)&_pragma=busy_timeout(30000)"
+}
+
+// Close closes the database of the store.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// update runs fn in a transaction that is committed if fn succeeds.
+func (s *Store) update(fn func(tx *sql.Tx) error) error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to update store: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to update store: %w", err)
+	}
+	return nil
+}
+
+// querier is what reads need of *sql.DB and *sql.Tx.
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// Enqueue adds reports that are not in the store yet as discovered and
+// returns how many were added. A report is known by its URL and its DocID.
+// Reports without member ID are resolved to one as they are added.
+func (s *Store) Enqueue(reports ...clerk.Report) (int, error) {
+	var added int
+	err := s.update(func(tx *sql.Tx) error {
+		now := time.Now()
+		for _, r := range reports {
+			if r.DocID == "" {
+				return fmt.Errorf("report %s has no DocID", r.URL)
+			}
+			if r.MemberID == "" && s.resolve != nil {
+				r.MemberID = s.resolve(r.Name, r.Office)
+			}
+			n, err := insertEntry(tx, Entry{Report: r, State: Discovered, Updated: now})
+			if err != nil {
+				return err
+			}
+			added += n
+		}
+		return nil
+	})
+	return added, err
+}
+
+// insertEntry adds e unless a report with its URL or DocID is stored, and
+// returns the number of entries added.
+func insertEntry(tx *sql.Tx, e Entry) (int, error) {
+	report, err := json.Marshal(e.Report)
+	if err != nil {
+		return 0, err
+	}
+	res, err := tx.Exec(`INSERT INTO reports (doc_id, url, member_id, year, amends, report, state, failed_in, attempts, last_error, next_retry, updated)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT DO NOTHING`,
+		e.DocID, e.URL, e.MemberID, e.Year, e.Amends, string(report), e.State, e.FailedIn, e.Attempts, e.LastError,
+		formatTime(e.NextRetry), formatTime(e.Updated))
+	if err != nil {
+		return 0, fmt.Errorf("failed to store report %s: %w", e.URL, err)
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+const entryColumns = `doc_id, url, member_id, year, amends, report, state, failed_in, attempts, last_error, next_retry, updated`
+
+func scanEntry(scan func(dest ...any) error) (Entry, error) {
+	var e Entry
+	var docID, url, memberID, amends, report, nextRetry, updated string
+	var year int
+	if err := scan(&docID, &url, &memberID, &year, &amends, &report,
+		&e.State, &e.FailedIn, &e.Attempts, &e.LastError, &nextRetry, &updated); err != nil {
+		return e, err
+	}
+	if err := json.Unmarshal([]byte(report), &e.Report); err != nil {
+		return e, fmt.Errorf("failed to read stored report %s: %w", docID, err)
+	}
+	e.DocID, e.URL, e.MemberID, e.Year, e.Amends = docID, url, memberID, year, amends
+	e.NextRetry = parseTime(nextRetry)
+	e.Updated = parseTime(updated)
+	return e, nil
+}
+
+func queryEntries(q querier, where string, args ...any) ([]Entry, error) {
+	rows, err := q.Query(`SELECT `+entryColumns+` FROM reports `+where+` ORDER BY rowid`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read store: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	for rows.Next() {
+		e, err := scanEntry(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// findEntry returns the entry of the report with URL url.
+func findEntry(q querier, url string) (Entry, error) {
+	e, err := scanEntry(q.QueryRow(`SELECT `+entryColumns+` FROM reports WHERE url = ?`, url).Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return e, fmt.Errorf("report %s is not in the store", url)
+	}
+	return e, err
+}
+
+// entries returns the entries matching where, logging failures to read the
+// store like a store without entries.
+func (s *Store) entries(where string, args ...any) []Entry {
+	entries, err := queryEntries(s.db, where, args...)
+	if err != nil {
+		logError(err)
+		return []Entry{}
+	}
+	return entries
+}
+
+// Entry returns the stored entry of a report.
+func (s *Store) Entry(r clerk.Report) (Entry, bool) {
+	entries := s.entries(`WHERE url = ?`, r.URL)
+	if len(entries) == 0 {
+		return Entry{}, false
+	}
+	return entries[0], true
+}
+
+// Report returns the stored entry of the report with DocID docID.
+func (s *Store) Report(docID string) (Entry, bool) {
+	entries := s.entries(`WHERE doc_id = ?`, docID)
+	if len(entries) == 0 {
+		return Entry{}, false
+	}
+	return entries[0], true
+}
+
+// EntryFilter selects stored entries. The zero filter selects all of them.
+type EntryFilter struct {
+	States     []State // only entries in one of the states
+	MemberID   string  // only entries of the member
+	Unresolved bool    // only entries without member ID
+}
+
+// Entries returns the stored entries f selects, in the order they were
+// discovered.
+func (s *Store) Entries(f EntryFilter) []Entry {
+	var where []string
+	var args []any
+	if len(f.States) > 0 {
+		where = append(where, `state IN (`+placeholders(len(f.States))+`)`)
+		for _, state := range f.States {
+			args = append(args, state)
+		}
+	}
+	if f.MemberID != "" {
+		where = append(where, `member_id = ?`)
+		args = append(args, f.MemberID)
+	}
+	if f.Unresolved {
+		where = append(where, `member_id = ''`)
+	}
+	return s.entries(whereClause(where), args...)
+}
+
+// Pending returns the reports that are due for processing, oldest first.
+func (s *Store) Pending(now time.Time) []Entry {
+	return s.entries(`WHERE state NOT IN (?, ?) AND (state != ? OR attempts < ? AND next_retry <= ?)`,
+		Notified, Skipped, Failed, MaxAttempts, formatTime(now))
+}
+
+// Len returns the number of reports that are not notified or skipped.
+func (s *Store) Len() int {
+	var n int
+	if err := s.db.QueryRow(`SELECT count(*) FROM reports WHERE state NOT IN (?, ?)`, Notified, Skipped).Scan(&n); err != nil {
+		logError(err)
+	}
+	return n
+}
+
+// Stuck returns failed reports and reports that have not moved on from an
+// intermediate state for longer than maxDelay.
+func (s *Store) Stuck(now time.Time) []Entry {
+	return s.entries(`WHERE state NOT IN (?, ?) AND (state = ? OR updated < ?)`,
+		Notified, Skipped, Failed, formatTime(now.Add(-maxDelay)))
+}
+
+// SetState moves reports on to state. Reaching Notified clears earlier
+// failures, so a later one is retried MaxAttempts times again.
+func (s *Store) SetState(state State, reports ...clerk.Report) error {
+	return s.update(func(tx *sql.Tx) error {
+		now := formatTime(time.Now())
+		for _, r := range reports {
+			query := `UPDATE reports SET state = ?, updated = ? WHERE url = ?`
+			if state == Notified {
+				query = `UPDATE reports SET state = ?, updated = ?, failed_in = '', attempts = 0, last_error = '', next_retry = '' WHERE url = ?`
+			}
+			res, err := tx.Exec(query, state, now, r.URL)
+			if err != nil {
+				return err
+			}
+			if n, _ := res.RowsAffected(); n == 0 {
+				return fmt.Errorf("report %s is not in the store", r.URL)
+			}
+		}
+		return nil
+	})
+}
+
+// Fail marks reports as failed in their current state. They are retried
+// with an exponential backoff until MaxAttempts is reached.
+func (s *Store) Fail(cause error, reports ...clerk.Report) error {
+	return s.update(func(tx *sql.Tx) error {
+		now := time.Now()
+		for _, r := range reports {
+			e, err := findEntry(tx, r.URL)
+			if err != nil {
+				return err
+			}
+			if e.State != Failed {
+				e.FailedIn = e.State
+			}
+			e.Attempts++
+			_, err = tx.Exec(`UPDATE reports SET state = ?, failed_in = ?, attempts = ?, last_error = ?, next_retry = ?, updated = ?
+				WHERE url = ?`, Failed, e.FailedIn, e.Attempts, cause.Error(),
+				formatTime(now.Add(backoff(e.Attempts))), formatTime(now), r.URL)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SaveTrades stores the trades extracted from report r, replacing the ones
+// stored before. The report must be in the store. Trades without member ID
+// get the one of the report, or the one their name resolves to.
+func (s *Store) SaveTrades(r clerk.Report, trades []trade.Trade) error {
+	return s.update(func(tx *sql.Tx) error {
+		e, err := findEntry(tx, r.URL)
+		if err != nil {
+			return err
+		}
+
+		replaced, err := queryTrades(tx, `WHERE report_id = ?`, e.DocID)
+		if err != nil {
+			return err
+		}
+
+		added := make([]*Trade, 0, len(trades))
+		for _, t := range trades {
+			t.ReportID = e.DocID
+			t.URL = e.URL
+			t.MemberID = s.memberID(e, t)
+			status := Accepted
+			if len(t.Issues) > 0 {
+				status = Review
+			}
+			added = append(added, &Trade{Status: status, Trade: t})
+		}
+
+		amends := e.Amends
+		if err := dedupe(tx, &e, added); err != nil {
+			return err
+		}
+		if e.Amends != amends {
+			if err := updateReport(tx, e.Report); err != nil {
+				return err
+			}
+		}
+
+		byKey := make(map[string]int)
+		bySame := make(map[string][]int)
+		for _, t := range added {
+			if err := insertTrade(tx, t); err != nil {
+				return err
+			}
+			if k := restateKey(t.Trade); k != "" && byKey[k] == 0 {
+				byKey[k] = t.ID
+			}
+			bySame[sameKey(t.Trade)] = append(bySame[sameKey(t.Trade)], t.ID)
+		}
+
+		// restatements of replaced trades move on to the new ones, and so
+		// do the lists they were sent to
+		for _, old := range replaced {
+			var restates any
+			if id := byKey[restateKey(old.Trade)]; id != 0 {
+				restates = id
+			}
+			if _, err := tx.Exec(`UPDATE trades SET restates = ? WHERE restates = ?`, restates, old.ID); err != nil {
+				return err
+			}
+			if ids := bySame[sameKey(old.Trade)]; len(ids) > 0 {
+				bySame[sameKey(old.Trade)] = ids[1:]
+				if _, err := tx.Exec(`INSERT INTO sent (list, trade_id) SELECT list, ? FROM sent WHERE trade_id = ?`,
+					ids[0], old.ID); err != nil {
+					return err
+				}
+			}
+			if _, err := tx.Exec(`DELETE FROM trades WHERE id = ?`, old.ID); err != nil {
+				return err
+			}
+		}
+
+		if err := supersede(tx, e.DocID); err != nil {
+			return err
+		}
+		if e.Amends != "" {
+			return supersede(tx, e.Amends)
+		}
+		return nil
+	})
+}
+
+// insertTrade stores t and sets its ID. A trade with an ID keeps it.
+func insertTrade(tx *sql.Tx, t *Trade) error {
+	data, err := json.Marshal(t.Trade)
+	if err != nil {
+		return err
+	}
+	var id, restates any
+	if t.ID != 0 {
+		id = t.ID
+	}
+	if t.Restates != 0 {
+		restates = t.Restates
+	}
+	res, err := tx.Exec(`INSERT INTO trades (id, report_id, status, restates, member_id, ticker, trade_date, disclosed, restate_key, trade)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, t.ReportID, t.Status, restates, t.MemberID, t.Ticker, formatDate(t.TradeDate), formatDate(t.DisclosedDate),
+		restateKey(t.Trade), string(data))
+	if err != nil {
+		return fmt.Errorf("failed to store trade of report %s: %w", t.ReportID, err)
+	}
+	newID, err := res.LastInsertId()
+	t.ID = int(newID)
+	return err
+}
+
+// updateReport stores the report details of r.
+func updateReport(tx *sql.Tx, r clerk.Report) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`UPDATE reports SET member_id = ?, year = ?, amends = ?, report = ? WHERE url = ?`,
+		r.MemberID, r.Year, r.Amends, string(data), r.URL)
+	return err
+}
+
+// dedupe links the trades of report e that restate trades of earlier reports
+// of the same member: the trades of an amendment, which is linked to the
+// report it amends, and all trades of a report that was filed twice. Other
+// trades of an original report are never restatements, even if they repeat
+// an earlier trade, as the same trade may well be made twice.
+func dedupe(tx *sql.Tx, e *Entry, trades []*Trade) error {
+	if len(trades) == 0 {
+		return nil
+	}
+
+	restates := make(map[*Trade]Trade)
+	from := make(map[string]int)
+	for _, t := range trades {
+		k := restateKey(t.Trade)
+		if k == "" {
+			continue
+		}
+		// the first trade of another report with the same key, leaving out
+		// the amendments of e, which restate e and not the other way round
+		var o Trade
+		err := tx.QueryRow(`SELECT id, report_id FROM trades WHERE restate_key = ? AND report_id != ? AND restates IS NULL
+			AND report_id NOT IN (SELECT doc_id FROM reports WHERE amends = ?) ORDER BY id LIMIT 1`,
+			k, e.DocID, e.DocID).Scan(&o.ID, &o.ReportID)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read store: %w", err)
+		}
+		restates[t] = o
+		from[o.ReportID]++
+	}
+	if !e.Amendment() && len(restates) < len(trades) {
+		return nil
+	}
+
+	for t, o := range restates {
+		t.Restates = o.ID
+	}
+	if e.Amendment() && e.Amends == "" {
+		for docID, n := range from {
+			if n > from[e.Amends] || n == from[e.Amends] && docID < e.Amends {
+				e.Amends = docID
+			}
+		}
+	}
+	return nil
+}
+
+// supersede marks the superseded trades of report original and of its
+// amendments, ordered by DocID. An amendment restates the whole report, so
+// the trades of earlier reports that the last report with trades does not
+// restate, directly or through an amendment in between, were corrected or
+// dropped.
+func supersede(tx *sql.Tx, original string) error {
+	chain := []string{original}
+	rows, err := tx.Query(`SELECT doc_id FROM reports WHERE amends = ? AND doc_id != ? ORDER BY doc_id`, original, original)
+	if err != nil {
+		return fmt.Errorf("failed to read store: %w", err)
+	}
+	for rows.Next() {
+		var docID string
+		if err := rows.Scan(&docID); err != nil {
+			rows.Close()
+			return err
+		}
+		chain = append(chain, docID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(chain) == 1 {
+		return nil
+	}
+
+	byReport := make(map[string][]Trade)
+	last := original
+	for _, docID := range chain {
+		trades, err := queryTrades(tx, `WHERE report_id = ?`, docID)
+		if err != nil {
+			return err
+		}
+		byReport[docID] = trades
+		if len(trades) > 0 {
+			last = docID
+		}
+	}
+
+	// trades survive in the last report with trades, and in earlier ones
+	// when a surviving trade of a later one restates them
+	end := slices.Index(chain, last)
+	restated := make(map[int]bool)
+	for i := end; i >= 0; i-- {
+		for _, t := range byReport[chain[i]] {
+			survives := i == end || restated[t.ID]
+			var superseded any
+			if !survives {
+				superseded = last
+			}
+			if _, err := tx.Exec(`UPDATE trades SET superseded = ? WHERE id = ?`, superseded, t.ID); err != nil {
+				return err
+			}
+			if survives && t.Restates != 0 {
+				restated[t.Restates] = true
+			}
+		}
+	}
+	return nil
+}
+
+// restateKey identifies a trade across reports by member, asset, date, type
+// and amount. Trades without a member have no key.
+func restateKey(t trade.Trade) string {
+	if t.MemberID == "" {
+		return ""
+	}
+	return tradeKey(t.MemberID, t)
+}
+
+// sameKey identifies a trade among the trades of the same report, which are
+// all filed by the same member, by its owner, asset, date, type and amount.
+func sameKey(t trade.Trade) string {
+	return tradeKey(t.Owner, t)
+}
+
+func tradeKey(who string, t trade.Trade) string {
+	asset := strings.ToUpper(t.Ticker)
+	if asset == "" {
+		asset = strings.Join(names.Words(t.Asset), " ")
+	}
+	date := t.Date
+	if !t.TradeDate.IsZero() {
+		date = t.TradeDate.Format(time.DateOnly)
+	}
+	amount := t.Amount
+	if t.AmountMin != 0 || t.AmountMax != 0 {
+		amount = fmt.Sprintf("%d-%d", t.AmountMin, t.AmountMax)
+	}
+	return strings.Join([]string{who, asset, date, string(t.Type), amount}, "|")
+}
+
+// SetAmendment marks report r as an amendment of the report with DocID
+// amends, or of an original found by its trades if amends is empty.
+func (s *Store) SetAmendment(r clerk.Report, amends string) error {
+	return s.update(func(tx *sql.Tx) error {
+		e, err := findEntry(tx, r.URL)
+		if err != nil {
+			return err
+		}
+		if amends == e.DocID {
+			amends = ""
+		}
+		if !strings.Contains(strings.ToLower(e.FilingType), "amend") {
+			e.FilingType = strings.TrimSpace(strings.TrimSuffix(e.FilingType, "Original") + " Amendment")
+		}
+		previous := e.Amends
+		if amends != "" {
+			e.Amends = amends
+		}
+		if err := updateReport(tx, e.Report); err != nil {
+			return err
+		}
+		if previous != "" && previous != e.Amends {
+			if err := supersede(tx, previous); err != nil {
+				return err
+			}
+		}
+		if e.Amends == "" {
+			return nil
+		}
+		return supersede(tx, e.Amends)
+	})
+}
+
+const tradeColumns = `id, status, restates, superseded, member_id, ticker, trade`
+
+func queryTrades(q querier, where string, args ...any) ([]Trade, error) {
+	rows, err := q.Query(`SELECT `+tradeColumns+` FROM trades `+where+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read store: %w", err)
+	}
+	defer rows.Close()
+
+	trades := []Trade{}
+	for rows.Next() {
+		var t Trade
+		var restates sql.NullInt64
+		var superseded sql.NullString
+		var memberID, ticker, data string
+		if err := rows.Scan(&t.ID, &t.Status, &restates, &superseded, &memberID, &ticker, &data); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(data), &t.Trade); err != nil {
+			return nil, fmt.Errorf("failed to read stored trade %d: %w", t.ID, err)
+		}
+		t.Restates, t.Superseded = int(restates.Int64), superseded.String
+		t.MemberID, t.Ticker = memberID, ticker
+		trades = append(trades, t)
+	}
+	return trades, rows.Err()
+}
+
+// trades returns the trades matching where, logging failures to read the
+// store like a store without trades.
+func (s *Store) trades(where string, args ...any) []Trade {
+	trades, err := queryTrades(s.db, where, args...)
+	if err != nil {
+		logError(err)
+		return []Trade{}
+	}
+	return trades
+}
+
+// Trades returns the stored trades of the given reports, in report order.
+func (s *Store) Trades(reports ...clerk.Report) []Trade {
+	var trades []Trade
+	for _, r := range reports {
+		trades = append(trades, s.trades(`WHERE report_id = ?`, r.DocID)...)
+	}
+	return trades
+}
+
+// accepted selects the trades for which Trade.Accepted returns true.
+const accepted = `status NOT IN ('` + string(Review) + `', '` + string(Rejected) + `') AND restates IS NULL AND superseded IS NULL`
+
+// TradeFilter selects stored trades. The zero filter selects all of them.
+type TradeFilter struct {
+	Accepted   bool   // only trades that may be listed and notified, see Trade.Accepted
+	Status     Status // only trades with the review status
+	ReportID   string // only trades of the report
+	MemberID   string // only trades of the member
+	Ticker     string // only trades of the ticker, in any case
+	Superseded string // only trades superseded by the amendment with this DocID
+
+	// only trades whose member name or asset contains Text, in any case,
+	// or whose ticker is Text
+	Text string
+
+	// only trades made, or disclosed, in the period; zero times leave it
+	// open. Trades whose date is unknown are left out of a period.
+	From, To                   time.Time
+	DisclosedFrom, DisclosedTo time.Time
+}
+
+// Query returns the stored trades f selects, in the order they were stored.
+func (s *Store) Query(f TradeFilter) []Trade {
+	var where []string
+	var args []any
+	if f.Accepted {
+		where = append(where, accepted)
+	}
+	for _, c := range []struct {
+		column, value string
+	}{
+		{"status", string(f.Status)},
+		{"report_id", f.ReportID},
+		{"member_id", f.MemberID},
+		{"ticker", f.Ticker},
+		{"superseded", f.Superseded},
+	} {
+		if c.value != "" {
+			where = append(where, c.column+` = ?`)
+			args = append(args, c.value)
+		}
+	}
+	if f.Text != "" {
+		like := "%" + strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(f.Text) + "%"
+		where = append(where, `(json_extract(trade, '$.Name') LIKE ? ESCAPE '\' OR json_extract(trade, '$.Asset') LIKE ? ESCAPE '\' OR ticker = ?)`)
+		args = append(args, like, like, f.Text)
+	}
+	where, args = period(where, args, "trade_date", f.From, f.To)
+	where, args = period(where, args, "disclosed", f.DisclosedFrom, f.DisclosedTo)
+	return s.trades(whereClause(where), args...)
+}
+
+// period adds the conditions that the date in column is known and between
+// from and to, unless both are zero.
+func period(where []string, args []any, column string, from, to time.Time) ([]string, []any) {
+	if from.IsZero() && to.IsZero() {
+		return where, args
+	}
+	where = append(where, column+` != ''`)
+	if !from.IsZero() {
+		where = append(where, column+` >= ?`)
+		args = append(args, formatDate(from))
+	}
+	if !to.IsZero() {
+		where = append(where, column+` <= ?`)
+		args = append(args, formatDate(to))
+	}
+	return where, args
+}
+
+// MemberStats counts the stored reports and accepted trades of a member.
+type MemberStats struct {
+	Reports    int
+	Trades     int
+	LastFiling string // filing date of the last discovered report that has one
+}
+
+// Members returns the stats of every member with stored reports, by member
+// ID.
+func (s *Store) Members() map[string]MemberStats {
+	stats := make(map[string]MemberStats)
+	rows, err := s.db.Query(`SELECT r.member_id, count(*), coalesce((SELECT json_extract(l.report, '$.FilingDate')
+		FROM reports l WHERE l.member_id = r.member_id AND coalesce(json_extract(l.report, '$.FilingDate'), '') != ''
+		ORDER BY l.rowid DESC LIMIT 1), '')
+		FROM reports r WHERE r.member_id != '' GROUP BY r.member_id`)
+	if err != nil {
+		logError(fmt.Errorf("failed to read store: %w", err))
+		return stats
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		var m MemberStats
+		if err := rows.Scan(&id, &m.Reports, &m.LastFiling); err != nil {
+			logError(err)
+			return stats
+		}
+		stats[id] = m
+	}
+
+	rows, err = s.db.Query(`SELECT member_id, count(*) FROM trades WHERE member_id != '' AND ` + accepted + ` GROUP BY member_id`)
+	if err != nil {
+		logError(fmt.Errorf("failed to read store: %w", err))
+		return stats
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		var n int
+		if err := rows.Scan(&id, &n); err != nil {
+			logError(err)
+			return stats
+		}
+		if m, ok := stats[id]; ok {
+			m.Trades = n
+			stats[id] = m
+		}
+	}
+	return stats
+}
+
+// SetStatus records the review decision of a trade.
+func (s *Store) SetStatus(id int, status Status) error {
+	return s.update(func(tx *sql.Tx) error {
+		res, err := tx.Exec(`UPDATE trades SET status = ? WHERE id = ?`, status, id)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return fmt.Errorf("trade %d is not in the store", id)
+		}
+		return nil
+	})
+}
+
+// MailingList is the list all trades are sent to when there are no
+// watchlists.
+const MailingList = ""
+
+// Unsent returns the accepted trades of extracted reports that were not sent
+// to list yet.
+func (s *Store) Unsent(list string) []Trade {
+	return s.trades(`WHERE `+accepted+` AND report_id IN (SELECT doc_id FROM reports WHERE state IN (?, ?))
+		AND id NOT IN (SELECT trade_id FROM sent WHERE list = ?)`, Extracted, Notified, list)
+}
+
+// StartList records that list is notified from now on, unless it was
+// before. The stored trades of reports of other filing years than years are
+// marked as sent to a new list, so it is not sent every trade ever stored.
+func (s *Store) StartList(list string, years []int) error {
+	return s.update(func(tx *sql.Tx) error {
+		res, err := tx.Exec(`INSERT INTO lists (name, started) VALUES (?, ?) ON CONFLICT DO NOTHING`, list, formatTime(time.Now()))
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return nil
+		}
+		args := []any{list}
+		for _, year := range years {
+			args = append(args, year)
+		}
+		_, err = tx.Exec(`INSERT INTO sent (list, trade_id) SELECT ?, id FROM trades
+			WHERE report_id IN (SELECT doc_id FROM reports WHERE year != 0 AND year NOT IN (`+placeholders(len(years))+`))
+			ON CONFLICT DO NOTHING`, args...)
+		return err
+	})
+}
+
+// MarkSent records that the trades with the given IDs were sent to list.
+func (s *Store) MarkSent(list string, ids ...int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return s.update(func(tx *sql.Tx) error {
+		for _, id := range ids {
+			if _, err := tx.Exec(`INSERT INTO sent (list, trade_id) VALUES (?, ?) ON CONFLICT DO NOTHING`, list, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// backoff doubles the retry delay with every attempt, up to maxDelay.
+func backoff(attempts int) time.Duration {
+	delay := retryDelay
+	for i := 1; i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
+
+// SetResolver sets the function that resolves member names, with the office
+// they filed for if known, to canonical member IDs. Reports and trades are
+// resolved when they are stored; see ResolveMembers for the ones stored
+// before their member was known.
+func (s *Store) SetResolver(resolve func(name, office string) string) {
+	s.resolve = resolve
+}
+
+// ResolveMembers resolves the stored reports and trades without member ID
+// again, e.g. after the roster was updated, and returns the number of
+// reports resolved.
+func (s *Store) ResolveMembers() (int, error) {
+	if s.resolve == nil {
+		return 0, nil
+	}
+
+	var resolved int
+	err := s.update(func(tx *sql.Tx) error {
+		entries, err := queryEntries(tx, `WHERE member_id = ''`)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if e.MemberID = s.resolve(e.Name, e.Office); e.MemberID != "" {
+				if err := updateReport(tx, e.Report); err != nil {
+					return err
+				}
+				resolved++
+			}
+		}
+
+		trades, err := queryTrades(tx, `WHERE member_id = ''`)
+		if err != nil {
+			return err
+		}
+		for _, t := range trades {
+			e, err := scanEntry(tx.QueryRow(`SELECT `+entryColumns+` FROM reports WHERE doc_id = ?`, t.ReportID).Scan)
+			if err != nil {
+				return err
+			}
+			if t.MemberID = s.memberID(e, t.Trade); t.MemberID == "" {
+				continue
+			}
+			data, err := json.Marshal(t.Trade)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Exec(`UPDATE trades SET member_id = ?, restate_key = ?, trade = ? WHERE id = ?`,
+				t.MemberID, restateKey(t.Trade), string(data), t.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return resolved, err
+}
+
+// memberID returns the member ID of trade t of report e: the one of the
+// report, or else the one its name resolves to.
+func (s *Store) memberID(e Entry, t trade.Trade) string {
+	switch {
+	case t.MemberID != "":
+		return t.MemberID
+	case e.MemberID != "":
+		return e.MemberID
+	case s.resolve != nil:
+		return s.resolve(t.Name, e.Office)
+	}
+	return ""
+}
+
+// placeholders returns n comma separated query parameters.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// whereClause joins conditions to a WHERE clause, which is empty without
+// conditions.
+func whereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(conditions, " AND ")
+}
+
+func logError(err error) {
+	log.Println("error:", err)
+}
+
+// Times are stored in UTC with a fixed number of digits, so they compare
+// like strings.
+const timeFormat = "2006-01-02T15:04:05.000000000Z07:00"
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(timeFormat)
+}
+
+func parseTime(s string) time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, s)
+	return t
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.DateOnly)
+}
@@ -0,0 +1,612 @@
+package store
+
+import (
+	"clerk_trades/archive"
+	"clerk_trades/clerk"
+	"clerk_trades/trade"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func openTest(t *testing.T, file string) *Store {
+	t.Helper()
+	s, err := Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func report(docID string) clerk.Report {
+	return clerk.NewReport("https://example.com/public_disc/ptr-pdfs/2025/" + docID + ".pdf")
+}
+
+func TestStoresShareChanges(t *testing.T) {
+	file := filepath.Join(t.TempDir(), FILE_STORE)
+	watch := openTest(t, file)
+	review := openTest(t, file)
+
+	r := report("20000001")
+	if _, err := watch.Enqueue(r); err != nil {
+		t.Fatal(err)
+	}
+	if err := watch.SaveTrades(r, []trade.Trade{{Name: "A", Issues: []string{"no date"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a trade approved by another process survives the next change of watch
+	trades := review.Trades(r)
+	if len(trades) != 1 || trades[0].Status != Review {
+		t.Fatalf("review sees %v, want one trade in review", trades)
+	}
+	if err := review.SetStatus(trades[0].ID, Approved); err != nil {
+		t.Fatal(err)
+	}
+	if err := watch.SetState(Notified, r); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := watch.Trades(r)[0].Status; got != Approved {
+		t.Errorf("status is %s after watch saved, want %s", got, Approved)
+	}
+	if e, _ := review.Entry(r); e.State != Notified {
+		t.Errorf("review sees state %s, want %s", e.State, Notified)
+	}
+}
+
+func TestStoresShareArchive(t *testing.T) {
+	file := filepath.Join(t.TempDir(), FILE_STORE)
+	dir := filepath.Join(filepath.Dir(file), archive.DIR_ARCHIVE)
+	watch, err := archive.Open(dir, openTest(t, file).Manifest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ingest, err := archive.Open(dir, openTest(t, file).Manifest())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, b := report("20000001"), report("20000002")
+	if err := watch.Put(a, []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ingest.Put(b, []byte("b")); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []clerk.Report{a, b} {
+		if _, ok := watch.Get(r); !ok {
+			t.Errorf("report %s archived by another store is missing", r.DocID)
+		}
+	}
+	if items, _, err := ingest.Check(); err != nil || len(items) != 2 {
+		t.Errorf("manifest has %d items, %v, want 2", len(items), err)
+	}
+
+	// a damaged file is not returned
+	if err := os.WriteFile(filepath.Join(dir, "2025", a.DocID+".pdf"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ingest.Get(a); ok {
+		t.Error("damaged report was returned")
+	}
+}
+
+func TestEnqueueKnowsDocIDs(t *testing.T) {
+	s := openTest(t, filepath.Join(t.TempDir(), FILE_STORE))
+
+	r := report("20000001")
+	local := r
+	local.URL = "file:///tmp/20000001.pdf"
+	added, err := s.Enqueue(r, local, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 1 {
+		t.Errorf("added %d reports, want 1", added)
+	}
+}
+
+func TestOpenReadOnly(t *testing.T) {
+	file := filepath.Join(t.TempDir(), FILE_STORE)
+	if _, err := OpenReadOnly(file); err == nil {
+		t.Fatal("missing store opened")
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Fatal("opening a missing store read-only created it")
+	}
+
+	w := openTest(t, file)
+	if _, err := w.Enqueue(report("20000001")); err != nil {
+		t.Fatal(err)
+	}
+	info, _ := os.Stat(file)
+
+	s, err := OpenReadOnly(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if n := len(s.Entries(EntryFilter{})); n != 1 {
+		t.Errorf("read %d reports, want 1", n)
+	}
+	if err := s.SetState(Notified, report("20000001")); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("change of read-only store returned %v", err)
+	}
+	s.SetResolver(func(string, string) string { return "P000197" })
+	if _, err := s.ResolveMembers(); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("resolving members of read-only store returned %v", err)
+	}
+	if after, _ := os.Stat(file); !after.ModTime().Equal(info.ModTime()) || after.Size() != info.Size() {
+		t.Error("read-only store was written")
+	}
+
+	// changes of other stores are read at once
+	if _, err := w.Enqueue(report("20000002")); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(s.Entries(EntryFilter{})); n != 2 {
+		t.Errorf("read %d reports after another store added one, want 2", n)
+	}
+}
+
+func TestAmendmentRestatesTrades(t *testing.T) {
+	s := openTest(t, filepath.Join(t.TempDir(), FILE_STORE))
+	s.SetResolver(func(string, string) string { return "P000197" })
+
+	original, amendment, twice := report("20000001"), report("20000002"), report("20000003")
+	amendment.FilingType = "PTR Amendment"
+	if _, err := s.Enqueue(original, amendment, twice); err != nil {
+		t.Fatal(err)
+	}
+
+	apple := trade.Trade{Name: "Nancy Pelosi", Asset: "Apple Inc. (AAPL)", Ticker: "AAPL", Type: "P", Date: "1/2/2025", Amount: "$1,001 - $15,000"}
+	msft := trade.Trade{Name: "Nancy Pelosi", Asset: "Microsoft Corp", Type: "S", Date: "1/3/2025", Amount: "$1,001 - $15,000"}
+	nvda := trade.Trade{Name: "Nancy Pelosi", Asset: "NVIDIA Corp", Type: "S", Date: "1/3/2025", Amount: "$1,001 - $15,000"}
+	for _, step := range []struct {
+		r      clerk.Report
+		trades []trade.Trade
+	}{
+		{original, []trade.Trade{apple, msft}},
+		{amendment, []trade.Trade{apple, nvda}},
+		{twice, []trade.Trade{apple, msft}},
+	} {
+		if err := s.SaveTrades(step.r, step.trades); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	restated := func(r clerk.Report) []bool {
+		var got []bool
+		for _, t := range s.Trades(r) {
+			got = append(got, t.Restates != 0)
+		}
+		return got
+	}
+	check := func(name string, got []bool, want ...bool) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("%s: restated %v, want %v", name, got, want)
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Errorf("%s: restated %v, want %v", name, got, want)
+				break
+			}
+		}
+	}
+	check("original", restated(original), false, false)
+	check("amendment", restated(amendment), true, false)
+	check("filed twice", restated(twice), true, true)
+
+	if e, _ := s.Entry(amendment); e.Amends != original.DocID {
+		t.Errorf("amendment amends %q, want %s", e.Amends, original.DocID)
+	}
+
+	// restatements follow the trades of a reprocessed original
+	if err := s.SaveTrades(original, []trade.Trade{apple, msft}); err != nil {
+		t.Fatal(err)
+	}
+	check("amendment after reprocessing", restated(amendment), true, false)
+	apples := s.Trades(original)
+	if got := s.Trades(amendment)[0].Restates; got != apples[0].ID {
+		t.Errorf("amendment restates trade %d, want %d", got, apples[0].ID)
+	}
+}
+
+func TestAmendmentSupersedesTrades(t *testing.T) {
+	apple := trade.Trade{Name: "Nancy Pelosi", Asset: "Apple Inc. (AAPL)", Ticker: "AAPL", Type: "P", Date: "1/2/2025", Amount: "$1,001 - $15,000"}
+	msft := trade.Trade{Name: "Nancy Pelosi", Asset: "Microsoft Corp", Type: "S", Date: "1/3/2025", Amount: "$1,001 - $15,000"}
+	corrected := msft
+	corrected.Amount = "$15,001 - $50,000"
+
+	for _, amendmentFirst := range []bool{false, true} {
+		t.Run(fmt.Sprintf("amendment first %v", amendmentFirst), func(t *testing.T) {
+			s := openTest(t, filepath.Join(t.TempDir(), FILE_STORE))
+			s.SetResolver(func(string, string) string { return "P000197" })
+			original, amendment := report("20000001"), report("20000002")
+			amendment.FilingType = "PTR Amendment"
+			amendment.Amends = original.DocID
+			if _, err := s.Enqueue(original, amendment); err != nil {
+				t.Fatal(err)
+			}
+
+			steps := []func() error{
+				func() error { return s.SaveTrades(original, []trade.Trade{apple, msft}) },
+				func() error { return s.SaveTrades(amendment, []trade.Trade{apple, corrected}) },
+			}
+			if amendmentFirst {
+				steps[0], steps[1] = steps[1], steps[0]
+			}
+			for _, step := range steps {
+				if err := step(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			checkAccepted := func(when string) {
+				t.Helper()
+				var accepted []string
+				for _, tr := range s.Query(TradeFilter{Accepted: true}) {
+					accepted = append(accepted, tr.Asset+" "+tr.Amount)
+				}
+				slices.Sort(accepted)
+				want := []string{"Apple Inc. (AAPL) $1,001 - $15,000", "Microsoft Corp $15,001 - $50,000"}
+				if !slices.Equal(accepted, want) {
+					t.Errorf("%s: accepted %q, want %q", when, accepted, want)
+				}
+			}
+			checkAccepted("stored")
+			if got := s.Trades(original)[1].Superseded; got != amendment.DocID {
+				t.Errorf("corrected trade is superseded by %q, want %s", got, amendment.DocID)
+			}
+
+			// reprocessing the original keeps its trade superseded
+			if err := s.SaveTrades(original, []trade.Trade{apple, msft}); err != nil {
+				t.Fatal(err)
+			}
+			checkAccepted("reprocessed")
+		})
+	}
+}
+
+func TestSentTrades(t *testing.T) {
+	s := openTest(t, filepath.Join(t.TempDir(), FILE_STORE))
+	r := report("20000001")
+	if _, err := s.Enqueue(r); err != nil {
+		t.Fatal(err)
+	}
+
+	apple := trade.Trade{Name: "A", Asset: "Apple Inc.", Type: "P", Date: "1/2/2025", Amount: "$1,001 - $15,000"}
+	msft := trade.Trade{Name: "A", Asset: "Microsoft Corp", Type: "S", Date: "1/3/2025", Amount: "$1,001 - $15,000"}
+	if err := s.SaveTrades(r, []trade.Trade{apple, apple, msft}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetState(Extracted, r); err != nil {
+		t.Fatal(err)
+	}
+	trades := s.Trades(r)
+	if err := s.MarkSent("leadership", trades[0].ID, trades[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(s.Unsent(MailingList)); n != 3 {
+		t.Errorf("%d trades unsent to the mailing list, want 3", n)
+	}
+
+	// sent trades stay sent when the report is extracted again
+	nvda := msft
+	nvda.Asset = "NVIDIA Corp"
+	if err := s.SaveTrades(r, []trade.Trade{apple, nvda, apple}); err != nil {
+		t.Fatal(err)
+	}
+	unsent := s.Unsent("leadership")
+	if len(unsent) != 1 || unsent[0].Asset != nvda.Asset {
+		t.Errorf("unsent after extracting again: %v, want %s", unsent, nvda.Asset)
+	}
+}
+
+func TestSkippedReports(t *testing.T) {
+	s := openTest(t, filepath.Join(t.TempDir(), FILE_STORE))
+	a, b := report("20000001"), report("20000002")
+	if _, err := s.Enqueue(a, b); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetState(Skipped, b); err != nil {
+		t.Fatal(err)
+	}
+
+	pending := s.Pending(time.Now())
+	if len(pending) != 1 || pending[0].DocID != a.DocID {
+		t.Errorf("pending %v, want only %s", pending, a.DocID)
+	}
+	if n := s.Len(); n != 1 {
+		t.Errorf("%d reports left to process, want 1", n)
+	}
+}
+
+func TestStartList(t *testing.T) {
+	s := openTest(t, filepath.Join(t.TempDir(), FILE_STORE))
+	old := clerk.NewReport("https://example.com/public_disc/ptr-pdfs/2019/20000001.pdf")
+	current := report("20000002")
+	later := clerk.NewReport("https://example.com/public_disc/ptr-pdfs/2019/20000003.pdf")
+	for _, r := range []clerk.Report{old, current} {
+		if _, err := s.Enqueue(r); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SaveTrades(r, []trade.Trade{{Name: "A", Asset: "Apple Inc."}}); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SetState(Extracted, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a new list is not sent the trades of earlier years stored before it
+	if err := s.StartList("leadership", []int{2025}); err != nil {
+		t.Fatal(err)
+	}
+	unsent := s.Unsent("leadership")
+	if len(unsent) != 1 || unsent[0].ReportID != current.DocID {
+		t.Errorf("unsent %v, want the trade of %s", unsent, current.DocID)
+	}
+
+	// but it is sent those of older reports stored later
+	if _, err := s.Enqueue(later); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveTrades(later, []trade.Trade{{Name: "A", Asset: "Microsoft Corp"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetState(Extracted, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.StartList("leadership", []int{2025}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(s.Unsent("leadership")); n != 2 {
+		t.Errorf("%d trades unsent after starting the list again, want 2", n)
+	}
+}
+
+func TestUnsentOfExtractedReports(t *testing.T) {
+	s := openTest(t, filepath.Join(t.TempDir(), FILE_STORE))
+	r := report("20000001")
+	if _, err := s.Enqueue(r); err != nil {
+		t.Fatal(err)
+	}
+	err := s.SaveTrades(r, []trade.Trade{{Name: "A", Asset: "Apple Inc."}, {Name: "A", Asset: "NVIDIA Corp", Issues: []string{"no date"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// trades are sent once their report is extracted, and only accepted ones
+	if n := len(s.Unsent(MailingList)); n != 0 {
+		t.Errorf("%d trades of a report not extracted yet are unsent, want 0", n)
+	}
+	if err := s.SetState(Extracted, r); err != nil {
+		t.Fatal(err)
+	}
+	unsent := s.Unsent(MailingList)
+	if len(unsent) != 1 || unsent[0].Asset != "Apple Inc." {
+		t.Errorf("unsent %v, want the accepted trade", unsent)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	s := openTest(t, filepath.Join(t.TempDir(), FILE_STORE))
+	a, b := report("20000001"), report("20000002")
+	a.MemberID = "P000197"
+	if _, err := s.Enqueue(a, b); err != nil {
+		t.Fatal(err)
+	}
+	date := func(s string) time.Time {
+		d, _ := time.Parse(time.DateOnly, s)
+		return d
+	}
+	apple := trade.Trade{Name: "Nancy Pelosi", Asset: "Apple Inc.", Ticker: "AAPL", TradeDate: date("2025-01-02"), DisclosedDate: date("2025-03-01")}
+	nvda := trade.Trade{Name: "Nancy Pelosi", Asset: "NVIDIA Corp 100% stake", Ticker: "NVDA", TradeDate: date("2025-02-03"), Issues: []string{"no amount"}}
+	msft := trade.Trade{Name: "John Doe", Asset: "Microsoft Corp", Ticker: "MSFT", DisclosedDate: date("2025-02-01")}
+	if err := s.SaveTrades(a, []trade.Trade{apple, nvda}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveTrades(b, []trade.Trade{msft}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		filter TradeFilter
+		want   []string
+	}{
+		{"all", TradeFilter{}, []string{"AAPL", "NVDA", "MSFT"}},
+		{"accepted", TradeFilter{Accepted: true}, []string{"AAPL", "MSFT"}},
+		{"review", TradeFilter{Status: Review}, []string{"NVDA"}},
+		{"member", TradeFilter{MemberID: "P000197"}, []string{"AAPL", "NVDA"}},
+		{"report", TradeFilter{ReportID: b.DocID}, []string{"MSFT"}},
+		{"ticker in any case", TradeFilter{Ticker: "aapl"}, []string{"AAPL"}},
+		{"name", TradeFilter{Text: "pelosi"}, []string{"AAPL", "NVDA"}},
+		{"asset", TradeFilter{Text: "corp"}, []string{"NVDA", "MSFT"}},
+		{"ticker", TradeFilter{Text: "msft"}, []string{"MSFT"}},
+		{"literal percent", TradeFilter{Text: "100%"}, []string{"NVDA"}},
+		{"traded since", TradeFilter{From: date("2025-01-15")}, []string{"NVDA"}},
+		{"traded until", TradeFilter{To: date("2025-01-02")}, []string{"AAPL"}},
+		{"disclosed", TradeFilter{DisclosedFrom: date("2025-01-01"), DisclosedTo: date("2025-02-01")}, []string{"MSFT"}},
+	} {
+		var got []string
+		for _, t := range s.Query(tc.filter) {
+			got = append(got, t.Ticker)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestResolveMembers(t *testing.T) {
+	s := openTest(t, filepath.Join(t.TempDir(), FILE_STORE))
+	known := map[string]string{"Pelosi, Nancy": "P000197"}
+	s.SetResolver(func(name, office string) string { return known[name] })
+
+	a, b := report("20000001"), report("20000002")
+	a.Name, b.Name = "Pelosi, Nancy", "Doe, John"
+	if _, err := s.Enqueue(a, b); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveTrades(b, []trade.Trade{{Name: "John Doe", Asset: "Apple Inc."}}); err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := s.Entry(a); e.MemberID != "P000197" {
+		t.Errorf("report was stored with member %q, want P000197", e.MemberID)
+	}
+	unresolved := s.Entries(EntryFilter{Unresolved: true})
+	if len(unresolved) != 1 || unresolved[0].DocID != b.DocID {
+		t.Fatalf("unresolved %v, want %s", unresolved, b.DocID)
+	}
+
+	// names the roster learns later are resolved on request
+	known["Doe, John"] = "D000001"
+	n, err := s.ResolveMembers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("resolved %d reports, want 1", n)
+	}
+	if got := s.Entries(EntryFilter{MemberID: "D000001"}); len(got) != 1 {
+		t.Errorf("%d reports of the resolved member, want 1", len(got))
+	}
+	if got := s.Query(TradeFilter{MemberID: "D000001"}); len(got) != 1 {
+		t.Errorf("%d trades of the resolved member, want 1", len(got))
+	}
+	if stats := s.Members(); stats["D000001"].Reports != 1 || stats["D000001"].Trades != 1 {
+		t.Errorf("stats of the resolved member %+v, want 1 report and 1 trade", stats["D000001"])
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{
+		1:  time.Hour,
+		2:  2 * time.Hour,
+		3:  4 * time.Hour,
+		5:  16 * time.Hour,
+		6:  24 * time.Hour,
+		20: 24 * time.Hour,
+	} {
+		if got := backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
+
+func TestDue(t *testing.T) {
+	now := time.Now()
+	for _, tc := range []struct {
+		e    Entry
+		want bool
+	}{
+		{Entry{State: Discovered}, true},
+		{Entry{State: Extracted}, true},
+		{Entry{State: Notified}, false},
+		{Entry{State: Skipped}, false},
+		{Entry{State: Failed, Attempts: 1, NextRetry: now}, true},
+		{Entry{State: Failed, Attempts: 1, NextRetry: now.Add(time.Minute)}, false},
+		{Entry{State: Failed, Attempts: MaxAttempts, NextRetry: now}, false},
+	} {
+		if got := tc.e.Due(now); got != tc.want {
+			t.Errorf("%s after %d attempts, retry in %s: due %v, want %v",
+				tc.e.State, tc.e.Attempts, tc.e.NextRetry.Sub(now), got, tc.want)
+		}
+	}
+}
+
+func TestFailedReports(t *testing.T) {
+	s := openTest(t, filepath.Join(t.TempDir(), FILE_STORE))
+	r := report("20000001")
+	if _, err := s.Enqueue(r); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetState(Downloaded, r); err != nil {
+		t.Fatal(err)
+	}
+
+	// a failed report is stuck and waits for its retry
+	start := time.Now()
+	if err := s.Fail(errors.New("no trades"), r); err != nil {
+		t.Fatal(err)
+	}
+	e, _ := s.Entry(r)
+	if e.State != Failed || e.FailedIn != Downloaded || e.Attempts != 1 || e.LastError != "no trades" {
+		t.Errorf("failed entry %+v, want failed in %s after 1 attempt", e, Downloaded)
+	}
+	if n := len(s.Pending(start)); n != 0 {
+		t.Errorf("%d reports pending before the retry, want 0", n)
+	}
+	if n := len(s.Stuck(start)); n != 1 {
+		t.Errorf("%d reports stuck, want 1", n)
+	}
+
+	// every attempt doubles the delay, the failed state is kept
+	for attempt := 2; attempt <= MaxAttempts; attempt++ {
+		e, _ := s.Entry(r)
+		if pending := s.Pending(e.NextRetry); len(pending) != 1 {
+			t.Fatalf("attempt %d: report is not pending at its retry", attempt)
+		}
+		if err := s.Fail(errors.New("no trades"), r); err != nil {
+			t.Fatal(err)
+		}
+		e, _ = s.Entry(r)
+		if e.FailedIn != Downloaded || e.Attempts != attempt {
+			t.Errorf("attempt %d: failed in %s after %d attempts", attempt, e.FailedIn, e.Attempts)
+		}
+		if delay := e.NextRetry.Sub(e.Updated); delay != backoff(attempt) {
+			t.Errorf("attempt %d: retried after %s, want %s", attempt, delay, backoff(attempt))
+		}
+	}
+
+	// after MaxAttempts it is given up, but still stuck
+	if n := len(s.Pending(start.Add(365 * 24 * time.Hour))); n != 0 {
+		t.Errorf("%d reports pending after %d attempts, want 0", n, MaxAttempts)
+	}
+	if n := len(s.Stuck(start)); n != 1 {
+		t.Errorf("%d reports stuck after giving up, want 1", n)
+	}
+
+	// success clears the failures, so a later one is retried again
+	if err := s.SetState(Notified, r); err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := s.Entry(r); e.Attempts != 0 || e.LastError != "" || e.FailedIn != "" || !e.NextRetry.IsZero() {
+		t.Errorf("notified entry %+v still has failures", e)
+	}
+	if err := s.Fail(errors.New("no trades"), r); err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := s.Entry(r); e.Attempts != 1 || e.FailedIn != Notified {
+		t.Errorf("failed again in %s after %d attempts, want in %s after 1", e.FailedIn, e.Attempts, Notified)
+	}
+}
+
+func TestStuckReports(t *testing.T) {
+	s := openTest(t, filepath.Join(t.TempDir(), FILE_STORE))
+	a, b := report("20000001"), report("20000002")
+	if _, err := s.Enqueue(a, b); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetState(Notified, b); err != nil {
+		t.Fatal(err)
+	}
+
+	// a report is stuck once it stays in an intermediate state too long
+	if n := len(s.Stuck(time.Now())); n != 0 {
+		t.Errorf("%d reports stuck at once, want 0", n)
+	}
+	stuck := s.Stuck(time.Now().Add(maxDelay + time.Minute))
+	if len(stuck) != 1 || stuck[0].DocID != a.DocID {
+		t.Errorf("stuck %v, want only %s", stuck, a.DocID)
+	}
+}
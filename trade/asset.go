@@ -0,0 +1,139 @@
+package trade
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TickerSource tells where the ticker of a trade came from.
+type TickerSource string
+
+const (
+	TickerFiling   TickerSource = "filing"   // printed in the report
+	TickerLLM      TickerSource = "llm"      // filled in by the extraction model
+	TickerResolver TickerSource = "resolver" // matched in the security master
+	TickerOverride TickerSource = "override" // set in the override table
+)
+
+// AssetTypes are the asset type codes reports put in square brackets after
+// an asset, e.g. "Apple Inc. (AAPL) [ST]".
+var AssetTypes = map[string]string{
+	"5C": "529 College Savings Plan",
+	"5F": "529 Portfolio",
+	"5P": "529 Prepaid Tuition Plan",
+	"AB": "Asset-Backed Securities",
+	"BA": "Bank Accounts, Money Market Accounts and CDs",
+	"BK": "Brokerage Accounts",
+	"CO": "Collectibles",
+	"CS": "Corporate Securities (Bonds and Notes)",
+	"CT": "Cryptocurrency",
+	"DB": "Defined Benefit Pension Plan",
+	"DO": "Debts Owed to the Filer",
+	"DS": "Delaware Statutory Trust",
+	"EF": "Exchange Traded Funds (ETF)",
+	"EQ": "Excepted/Qualified Blind Trust",
+	"ET": "Exchange Traded Notes",
+	"FA": "Farms",
+	"FE": "Foreign Exchange (Currencies)",
+	"FN": "Fixed Annuity",
+	"FU": "Futures",
+	"GS": "Government Securities and Agency Debt",
+	"HE": "Hedge Funds & Private Equity Funds (EIF)",
+	"HN": "Hedge Funds & Private Equity Funds (non-EIF)",
+	"IC": "Investment Club",
+	"IH": "IRA (Held in Cash)",
+	"IP": "Intellectual Property & Royalties",
+	"IR": "IRA",
+	"MA": "Managed Accounts",
+	"MF": "Mutual Funds",
+	"MO": "Mineral/Oil/Solar Energy Rights",
+	"OI": "Ownership Interest (Holding Investments)",
+	"OL": "Ownership Interest (Engaged in a Trade or Business)",
+	"OP": "Options",
+	"OT": "Other",
+	"PE": "Pensions",
+	"PM": "Precious Metals",
+	"PS": "Stock (Not Publicly Traded)",
+	"RE": "Real Estate Investment Trust (REIT)",
+	"RP": "Real Property",
+	"RS": "Restricted Stock Units (RSUs)",
+	"SA": "Stock Appreciation Right",
+	"ST": "Stocks (including ADRs)",
+	"TR": "Trust",
+	"VA": "Variable Annuity",
+	"VI": "Variable Insurance",
+	"WU": "Whole/Universal Insurance",
+}
+
+var (
+	assetTypeRe  = regexp.MustCompile(`\[([0-9A-Z]{2})\]\s*$`)
+	optionTypeRe = regexp.MustCompile(`(?i)\b(call|put)s?\b`)
+	strikeRe     = regexp.MustCompile(`(?i)strike(?: price)?(?: of)?\s*\$?\s*([\d,]+(?:\.\d+)?)`)
+	expiresRe    = regexp.MustCompile(`(?i)expir\w*(?: date)?(?: of| on)?\s*(\d{1,2}/\d{1,2}/\d{2,4})`)
+)
+
+// AssetTypeOf returns the asset type code at the end of an asset name.
+func AssetTypeOf(asset string) string {
+	if m := assetTypeRe.FindStringSubmatch(asset); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// ParseOption reads call/put, strike price and expiration date of an
+// options trade from its description, e.g. "Purchased 50 call options with a
+// strike price of $150 and an expiration date of 1/16/2026."
+func ParseOption(t *Trade, description string) {
+	if m := optionTypeRe.FindStringSubmatch(description); m != nil && t.OptionType == "" {
+		kind := strings.ToLower(m[1])
+		t.OptionType = strings.ToUpper(kind[:1]) + kind[1:]
+	}
+	if m := strikeRe.FindStringSubmatch(description); m != nil && t.Strike == 0 {
+		if v, err := strconv.ParseFloat(strings.ReplaceAll(m[1], ",", ""), 64); err == nil {
+			t.Strike = v
+		}
+	}
+	if m := expiresRe.FindStringSubmatch(description); m != nil && t.Expiration == "" {
+		t.Expiration = m[1]
+	}
+}
+
+// IsOption reports whether t is an options trade.
+func (t Trade) IsOption() bool {
+	return t.AssetType == "OP" || t.OptionType != ""
+}
+
+// Option describes the contract of an options trade, e.g.
+// "Call $150 exp. 1/16/2026". It is empty for other trades.
+func (t Trade) Option() string {
+	if !t.IsOption() {
+		return ""
+	}
+	var parts []string
+	if t.OptionType != "" {
+		parts = append(parts, t.OptionType)
+	}
+	if t.Strike != 0 {
+		parts = append(parts, "$"+strconv.FormatFloat(t.Strike, 'f', -1, 64))
+	}
+	if t.Expiration != "" {
+		parts = append(parts, "exp. "+t.Expiration)
+	}
+	if len(parts) == 0 {
+		return "details unknown"
+	}
+	return strings.Join(parts, " ")
+}
+
+// AssetClass returns the name of the asset type of t.
+func (t Trade) AssetClass() string {
+	if name, ok := AssetTypes[t.AssetType]; ok {
+		return name
+	}
+	if t.AssetType != "" {
+		return fmt.Sprintf("unknown (%s)", t.AssetType)
+	}
+	return ""
+}
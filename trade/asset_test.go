@@ -0,0 +1,59 @@
+package trade
+
+import "testing"
+
+func TestParseOption(t *testing.T) {
+	tests := []struct {
+		name        string
+		trade       Trade
+		description string
+		want        Trade
+	}{
+		{"full", Trade{},
+			"Purchased 50 call options with a strike price of $150 and an expiration date of 1/16/2026.",
+			Trade{OptionType: "Call", Strike: 150, Expiration: "1/16/2026"}},
+		{"put", Trade{},
+			"Sold 20 PUTS, strike $1,250.50, expires 12/19/25",
+			Trade{OptionType: "Put", Strike: 1250.50, Expiration: "12/19/25"}},
+		{"expiration on", Trade{},
+			"Call options expiring on 06/20/2025",
+			Trade{OptionType: "Call", Expiration: "06/20/2025"}},
+		{"only the kind", Trade{}, "Exercised call options", Trade{OptionType: "Call"}},
+		{"no option", Trade{}, "Purchased 100 shares", Trade{}},
+		{"no call inside words", Trade{}, "Recalled shares with a strike price of 40", Trade{Strike: 40}},
+		{"keeps extracted fields",
+			Trade{OptionType: "Put", Strike: 90, Expiration: "1/17/2025"},
+			"Purchased 50 call options with a strike price of $150 and an expiration date of 1/16/2026.",
+			Trade{OptionType: "Put", Strike: 90, Expiration: "1/17/2025"}},
+		{"fills missing fields", Trade{OptionType: "Call"},
+			"Purchased 50 call options with a strike price of $150.",
+			Trade{OptionType: "Call", Strike: 150}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.trade
+			ParseOption(&got, tt.description)
+			if got.OptionType != tt.want.OptionType || got.Strike != tt.want.Strike || got.Expiration != tt.want.Expiration {
+				t.Errorf("got %s $%v exp. %q, want %s $%v exp. %q",
+					got.OptionType, got.Strike, got.Expiration, tt.want.OptionType, tt.want.Strike, tt.want.Expiration)
+			}
+		})
+	}
+}
+
+func TestOption(t *testing.T) {
+	tests := []struct {
+		trade Trade
+		want  string
+	}{
+		{Trade{AssetType: "OP", OptionType: "Call", Strike: 150, Expiration: "1/16/2026"}, "Call $150 exp. 1/16/2026"},
+		{Trade{OptionType: "Put", Strike: 1250.5}, "Put $1250.5"},
+		{Trade{AssetType: "OP"}, "details unknown"},
+		{Trade{AssetType: "ST", Strike: 150}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.trade.Option(); got != tt.want {
+			t.Errorf("Option() of %+v is %q, want %q", tt.trade, got, tt.want)
+		}
+	}
+}
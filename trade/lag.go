@@ -0,0 +1,40 @@
+package trade
+
+import (
+	"fmt"
+	"math"
+)
+
+// DisclosureDays is the number of days the STOCK Act gives to disclose a
+// trade.
+const DisclosureDays = 45
+
+// Lag returns the number of days between the trade and its disclosure. It
+// is unknown for trades whose dates could not be normalized, and for trades
+// of reports without filing date.
+func (t Trade) Lag() (int, bool) {
+	if t.TradeDate.IsZero() || t.DisclosedDate.IsZero() {
+		return 0, false
+	}
+	return int(math.Round(t.DisclosedDate.Sub(t.TradeDate).Hours() / 24)), true
+}
+
+// Late reports whether the trade was disclosed after DisclosureDays.
+func (t Trade) Late() bool {
+	lag, ok := t.Lag()
+	return ok && lag > DisclosureDays
+}
+
+// LagText returns the lag for display, e.g. "12 days" or "61 days (late)".
+func (t Trade) LagText() string {
+	lag, ok := t.Lag()
+	switch {
+	case !ok:
+		return ""
+	case t.Late():
+		return fmt.Sprintf("%d days (late)", lag)
+	case lag == 1:
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", lag)
+}
@@ -0,0 +1,44 @@
+package trade
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLag(t *testing.T) {
+	date := func(s string) time.Time {
+		d, err := ParseDate(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	tests := []struct {
+		name     string
+		trade    Trade
+		lag      int
+		ok, late bool
+	}{
+		{"only notification date", Trade{TradeDate: date("01/02/2025"), FiledDate: date("03/01/2025")}, 0, false, false},
+		{"filing date", Trade{TradeDate: date("01/02/2025"), FiledDate: date("01/10/2025"), DisclosedDate: date("03/01/2025")}, 58, true, true},
+		{"45 days", Trade{TradeDate: date("01/01/2025"), DisclosedDate: date("02/15/2025")}, 45, true, false},
+		{"46 days", Trade{TradeDate: date("01/01/2025"), DisclosedDate: date("02/16/2025")}, 46, true, true},
+		{"same day", Trade{TradeDate: date("01/02/2025"), DisclosedDate: date("01/02/2025")}, 0, true, false},
+		{"before the trade", Trade{TradeDate: date("01/10/2025"), DisclosedDate: date("01/02/2025")}, -8, true, false},
+		{"one day before", Trade{TradeDate: date("01/10/2025"), DisclosedDate: date("01/09/2025")}, -1, true, false},
+		{"no trade date", Trade{DisclosedDate: date("01/10/2025")}, 0, false, false},
+		{"no disclosure", Trade{TradeDate: date("01/10/2025")}, 0, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lag, ok := tt.trade.Lag()
+			if lag != tt.lag || ok != tt.ok {
+				t.Errorf("lag is %d, %v, want %d, %v", lag, ok, tt.lag, tt.ok)
+			}
+			if late := tt.trade.Late(); late != tt.late {
+				t.Errorf("late is %v, want %v", late, tt.late)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package trade
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TxType is the kind of a transaction.
+type TxType string
+
+const (
+	Purchase    TxType = "Purchase"
+	Sale        TxType = "Sale"
+	PartialSale TxType = "Partial Sale"
+	Exchange    TxType = "Exchange"
+)
+
+// Band is an amount range of the disclosure forms. Max is 0 for the open
+// ended "Over" bands.
+type Band struct {
+	Min, Max int64
+}
+
+// Bands are the statutory value ranges a transaction amount is reported in.
+var Bands = []Band{
+	{1_001, 15_000},
+	{15_001, 50_000},
+	{50_001, 100_000},
+	{100_001, 250_000},
+	{250_001, 500_000},
+	{500_001, 1_000_000},
+	{1_000_001, 5_000_000},
+	{5_000_001, 25_000_000},
+	{25_000_001, 50_000_000},
+	{50_000_001, 0}, // Over $50,000,000
+	{1_000_001, 0},  // Spouse/DC Over $1,000,000
+}
+
+var dateLayouts = []string{
+	"01/02/2006",
+	"1/2/2006",
+	"01/02/06",
+	"1/2/06",
+	"2006-01-02",
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"02-Jan-2006",
+}
+
+var amountRe = regexp.MustCompile(`\$?\s*([\d,]+)`)
+
+// Normalize parses the free-form fields of t into TradeDate, FiledDate,
+// AmountMin, AmountMax and a canonical Type. Fields that do not parse are
+// left zero and listed in t.ParseErrors.
+func Normalize(t *Trade) {
+	t.ParseErrors = nil
+
+	if t.AssetType == "" {
+		t.AssetType = AssetTypeOf(t.Asset)
+	}
+	if t.IsOption() && t.Description != "" {
+		ParseOption(t, t.Description)
+	}
+
+	if typ, ok := parseType(string(t.Type)); ok {
+		t.Type = typ
+	} else {
+		t.ParseErrors = append(t.ParseErrors, fmt.Sprintf("unknown transaction type %q", t.Type))
+	}
+
+	var err error
+	if t.TradeDate, err = ParseDate(t.Date); err != nil {
+		t.ParseErrors = append(t.ParseErrors, fmt.Sprintf("invalid date %q", t.Date))
+	}
+	if t.FiledDate, err = ParseDate(t.Filed); err != nil {
+		t.ParseErrors = append(t.ParseErrors, fmt.Sprintf("invalid notification date %q", t.Filed))
+	}
+	if t.AmountMin, t.AmountMax, err = ParseAmount(t.Amount); err != nil {
+		t.ParseErrors = append(t.ParseErrors, fmt.Sprintf("invalid amount %q", t.Amount))
+	}
+}
+
+// Normalized reports whether every field of t could be normalized.
+func (t Trade) Normalized() bool {
+	return len(t.ParseErrors) == 0
+}
+
+func parseType(s string) (TxType, bool) {
+	s = strings.ToLower(strings.Join(strings.Fields(s), " "))
+	switch s {
+	case "p", "purchase", "buy":
+		return Purchase, true
+	case "s", "sale", "sell", "sale (full)", "s (full)":
+		return Sale, true
+	case "s (partial)", "partial sale", "sale (partial)", "partial":
+		return PartialSale, true
+	case "e", "exchange":
+		return Exchange, true
+	}
+	return TxType(s), false
+}
+
+// ParseDate parses the date formats found in reports.
+func ParseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if d, err := time.Parse(layout, s); err == nil {
+			return d, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+// ParseAmount parses an amount range like "$1,001 - $15,000" or an open
+// ended one like "Over $50,000,000", for which max is 0.
+func ParseAmount(s string) (int64, int64, error) {
+	matches := amountRe.FindAllStringSubmatch(s, -1)
+	var values []int64
+	for _, m := range matches {
+		v, err := strconv.ParseInt(strings.ReplaceAll(m[1], ",", ""), 10, 64)
+		if err != nil {
+			continue
+		}
+		values = append(values, v)
+	}
+
+	switch {
+	case len(values) == 2 && values[0] <= values[1]:
+		return values[0], values[1], nil
+	case len(values) == 1 && strings.Contains(strings.ToLower(s), "over"):
+		return values[0] + 1, 0, nil
+	}
+	return 0, 0, fmt.Errorf("invalid amount %q", s)
+}
+
+// KnownBand reports whether min and max are one of the statutory Bands.
+func KnownBand(min, max int64) bool {
+	for _, b := range Bands {
+		if b.Min == min && b.Max == max {
+			return true
+		}
+	}
+	return false
+}
+
+// SortByDate sorts trades by transaction date, oldest first. Trades without
+// a parsed date come last.
+func SortByDate(trades []Trade) {
+	sort.SliceStable(trades, func(i, j int) bool {
+		a, b := trades[i].TradeDate, trades[j].TradeDate
+		if a.IsZero() || b.IsZero() {
+			return !a.IsZero()
+		}
+		return a.Before(b)
+	})
+}
@@ -0,0 +1,143 @@
+package trade
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		amount   string
+		min, max int64
+		ok       bool
+	}{
+		{"$1,001 - $15,000", 1_001, 15_000, true},
+		{"$1,000,001 - $5,000,000", 1_000_001, 5_000_000, true},
+		{"$15,001-$50,000", 15_001, 50_000, true},
+		{"1,001 - 15,000", 1_001, 15_000, true},
+		{"$ 50,001 - $ 100,000", 50_001, 100_000, true},
+		{"Over $50,000,000", 50_000_001, 0, true},
+		{"Spouse/DC Over $1,000,000", 1_000_001, 0, true},
+		{"$15,000 - $1,001", 0, 0, false},
+		{"$1,001", 0, 0, false},
+		{"$1,001 - $15,000 - $50,000", 0, 0, false},
+		{"None", 0, 0, false},
+		{"", 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.amount, func(t *testing.T) {
+			min, max, err := ParseAmount(tt.amount)
+			if ok := err == nil; ok != tt.ok || min != tt.min || max != tt.max {
+				t.Errorf("got %d, %d, %v, want %d, %d, ok %v", min, max, err, tt.min, tt.max, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	want := time.Date(2025, time.January, 2, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		date string
+		ok   bool
+	}{
+		{"01/02/2025", true},
+		{"1/2/2025", true},
+		{"01/02/25", true},
+		{"1/2/25", true},
+		{"2025-01-02", true},
+		{"January 2, 2025", true},
+		{"Jan 2, 2025", true},
+		{"02-Jan-2025", true},
+		{"  01/02/2025 ", true},
+		{"13/02/2025", false},
+		{"02.01.2025", false},
+		{"01/02", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.date, func(t *testing.T) {
+			d, err := ParseDate(tt.date)
+			if !tt.ok {
+				if err == nil {
+					t.Errorf("parsed as %v, want an error", d)
+				}
+				return
+			}
+			if err != nil || !d.Equal(want) {
+				t.Errorf("got %v, %v, want %v", d, err, want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	day := func(month time.Month, d int) time.Time { return time.Date(2025, month, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name        string
+		trade       Trade
+		typ         TxType
+		date, filed time.Time
+		min, max    int64
+		errors      []string
+	}{
+		{
+			name:  "valid",
+			trade: Trade{Type: "P", Date: "01/02/2025", Filed: "01/10/2025", Amount: "$1,001 - $15,000"},
+			typ:   Purchase, date: day(1, 2), filed: day(1, 10), min: 1_001, max: 15_000,
+		},
+		{
+			name:  "partial sale",
+			trade: Trade{Type: "S (partial)", Date: "01/02/2025", Filed: "01/10/2025", Amount: "Over $50,000,000"},
+			typ:   PartialSale, date: day(1, 2), filed: day(1, 10), min: 50_000_001,
+		},
+		{
+			name:  "long forms",
+			trade: Trade{Type: " Sale  (Full) ", Date: "January 2, 2025", Filed: "2025-01-10", Amount: "$15,001-$50,000"},
+			typ:   Sale, date: day(1, 2), filed: day(1, 10), min: 15_001, max: 50_000,
+		},
+		{
+			name:  "unknown type",
+			trade: Trade{Type: "Gift", Date: "01/02/2025", Filed: "01/10/2025", Amount: "$1,001 - $15,000"},
+			typ:   "Gift", date: day(1, 2), filed: day(1, 10), min: 1_001, max: 15_000,
+			errors: []string{`unknown transaction type "Gift"`},
+		},
+		{
+			name:  "unparsable dates",
+			trade: Trade{Type: "E", Date: "13/02/2025", Filed: "", Amount: "$1,001 - $15,000"},
+			typ:   Exchange, min: 1_001, max: 15_000,
+			errors: []string{`invalid date "13/02/2025"`, `invalid notification date ""`},
+		},
+		{
+			name:  "unparsable amount",
+			trade: Trade{Type: "P", Date: "01/02/2025", Filed: "01/10/2025", Amount: "None"},
+			typ:   Purchase, date: day(1, 2), filed: day(1, 10),
+			errors: []string{`invalid amount "None"`},
+		},
+		{
+			// parsed, but flagged by Rules.Validate
+			name:  "unknown amount band",
+			trade: Trade{Type: "P", Date: "01/02/2025", Filed: "01/10/2025", Amount: "$1,000 - $2,000"},
+			typ:   Purchase, date: day(1, 2), filed: day(1, 10), min: 1_000, max: 2_000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := tt.trade
+			tr.ParseErrors = []string{"left from an earlier run"}
+			Normalize(&tr)
+			if tr.Type != tt.typ {
+				t.Errorf("type %q, want %q", tr.Type, tt.typ)
+			}
+			if !tr.TradeDate.Equal(tt.date) || !tr.FiledDate.Equal(tt.filed) {
+				t.Errorf("dates %v, %v, want %v, %v", tr.TradeDate, tr.FiledDate, tt.date, tt.filed)
+			}
+			if tr.AmountMin != tt.min || tr.AmountMax != tt.max {
+				t.Errorf("amount %d - %d, want %d - %d", tr.AmountMin, tr.AmountMax, tt.min, tt.max)
+			}
+			if !slices.Equal(tr.ParseErrors, tt.errors) || tr.Normalized() != (len(tt.errors) == 0) {
+				t.Errorf("parse errors %q, want %q", tr.ParseErrors, tt.errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package trade
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Trade is a single transaction listed in a Periodic Transaction Report.
+type Trade struct {
+	Name   string `json:"Name"`
+	Owner  string `json:"Owner"` // Self, SP (spouse), JT (joint) or DC (dependent child)
+	Asset  string `json:"Asset"`
+	Ticker string `json:"Ticker"`
+	Type   TxType `json:"Type"`
+	Date   string `json:"Date"`
+	Filed  string `json:"Filed"`
+	Amount string `json:"Amount"`
+	Cap    bool   `json:"Cap"`
+
+	AssetType   string  `json:"AssetType"`   // code like ST (stocks) or OP (options), see AssetTypes
+	Description string  `json:"Description"` // free text filed with the transaction
+	OptionType  string  `json:"OptionType"`  // Call or Put, options only
+	Strike      float64 `json:"Strike"`      // options only
+	Expiration  string  `json:"Expiration"`  // options only
+
+	// set by the extractor and the security master
+	TickerSource TickerSource `json:"TickerSource,omitempty"`
+	CUSIP        string       `json:"CUSIP,omitempty"`
+
+	// filing date of the report the trade was disclosed in, if known. The
+	// notification date (FiledDate) is no substitute, as it is the date the
+	// filer was notified of the trade.
+	DisclosedDate time.Time `json:"DisclosedDate"`
+
+	// parsed from the fields above by Normalize
+	TradeDate   time.Time `json:"TradeDate"`
+	FiledDate   time.Time `json:"FiledDate"`
+	AmountMin   int64     `json:"AmountMin"`
+	AmountMax   int64     `json:"AmountMax"` // 0 for open ended amounts
+	ParseErrors []string  `json:"ParseErrors,omitempty"`
+
+	// problems found by Rules.Validate
+	Issues []string `json:"Issues,omitempty"`
+
+	// report the trade was extracted from
+	ReportID string `json:"ReportID"`
+	URL      string `json:"URL"`
+
+	// canonical ID of the member who filed the trade, see roster
+	MemberID string `json:"MemberID,omitempty"`
+}
+
+func PrintTrades(trades []Trade) string {
+	output := "\n"
+	for _, trade := range trades {
+		output += fmt.Sprintf("Name:    %-20s\n", trade.Name)
+		if trade.MemberID != "" {
+			output += fmt.Sprintf("Member:  %-20s\n", trade.MemberID)
+		}
+		output += fmt.Sprintf("Owner:   %-20s\n", trade.Owner)
+		output += fmt.Sprintf("Asset:   %-20s\n", trade.Asset)
+		if trade.AssetType != "" {
+			output += fmt.Sprintf("Class:   %-20s\n", trade.AssetClass())
+		}
+		if trade.IsOption() {
+			output += fmt.Sprintf("Option:  %-20s\n", trade.Option())
+		}
+		if trade.TickerSource != "" && trade.TickerSource != TickerFiling && trade.Ticker != "" {
+			output += fmt.Sprintf("Ticker:  %-20s\n", trade.Ticker+" ("+string(trade.TickerSource)+")")
+		} else {
+			output += fmt.Sprintf("Ticker:  %-20s\n", trade.Ticker)
+		}
+		if trade.CUSIP != "" {
+			output += fmt.Sprintf("CUSIP:   %-20s\n", trade.CUSIP)
+		}
+		output += fmt.Sprintf("Type:    %-20s\n", trade.Type)
+		output += fmt.Sprintf("Date:    %-20s\n", trade.Date)
+		output += fmt.Sprintf("Filed:   %-20s\n", trade.Filed)
+		if lag := trade.LagText(); lag != "" {
+			output += fmt.Sprintf("Lag:     %-20s\n", lag)
+		}
+		output += fmt.Sprintf("Amount:  %-20s\n", trade.Amount)
+		output += fmt.Sprintf("Cap:     %-20v\n", trade.Cap)
+		output += fmt.Sprintf("Report:  %-20s\n", trade.URL)
+		if len(trade.Issues) > 0 {
+			output += fmt.Sprintf("Issues:  %-20s\n", strings.Join(trade.Issues, "; "))
+		} else if !trade.Normalized() {
+			output += fmt.Sprintf("Flags:   %-20s\n", strings.Join(trade.ParseErrors, "; "))
+		}
+		output += "\n"
+	}
+	return output
+}
@@ -0,0 +1,137 @@
+package trade
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+const FILE_RULES = "rules.json"
+
+// Rules configure which checks Validate runs on a trade. They are built by
+// LoadRules or DefaultRules, which compile TickerFormat.
+type Rules struct {
+	Required         []string `json:"Required"`         // fields that must not be empty
+	DateInFilingYear bool     `json:"DateInFilingYear"` // trade date in the filing year or the one before
+	FiledAfterDate   bool     `json:"FiledAfterDate"`   // notification date not before the trade date
+	KnownAmountBand  bool     `json:"KnownAmountBand"`  // amount is one of the statutory Bands
+	TickerFormat     string   `json:"TickerFormat"`     // regular expression a ticker must match, if set
+
+	tickerRe *regexp.Regexp
+}
+
+// DefaultRules returns the rules used without rules file.
+func DefaultRules() Rules {
+	return Rules{
+		Required:         []string{"Name", "Asset", "Type", "Date", "Filed", "Amount"},
+		DateInFilingYear: true,
+		FiledAfterDate:   true,
+		KnownAmountBand:  true,
+		TickerFormat:     defaultTickerFormat.String(),
+		tickerRe:         defaultTickerFormat,
+	}
+}
+
+var defaultTickerFormat = regexp.MustCompile(`^[A-Z]{1,5}([.\-][A-Z]{1,2})?$`)
+
+// LoadRules reads the rules from file. Rules missing from the file, or the
+// whole file, default to DefaultRules.
+func LoadRules(file string) (Rules, error) {
+	rules := DefaultRules()
+	data, err := os.ReadFile(file)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &rules); err != nil {
+			return rules, fmt.Errorf("failed to read rules %s: %w", file, err)
+		}
+	case !os.IsNotExist(err):
+		return rules, fmt.Errorf("failed to read rules %s: %w", file, err)
+	}
+	if err := rules.compile(); err != nil {
+		return rules, fmt.Errorf("invalid rules %s: %w", file, err)
+	}
+	return rules, nil
+}
+
+func (r *Rules) compile() error {
+	for _, field := range r.Required {
+		if _, ok := fieldValue(Trade{}, field); !ok {
+			return fmt.Errorf("unknown required field %q", field)
+		}
+	}
+
+	r.tickerRe = nil
+	if r.TickerFormat == "" {
+		return nil
+	}
+	re, err := regexp.Compile(r.TickerFormat)
+	if err != nil {
+		return fmt.Errorf("invalid ticker format %q: %v", r.TickerFormat, err)
+	}
+	r.tickerRe = re
+	return nil
+}
+
+// Validate checks a normalized trade of a report filed in filingYear and
+// returns the issues found, including the fields Normalize could not parse.
+func (r Rules) Validate(t Trade, filingYear int) []string {
+	issues := append([]string(nil), t.ParseErrors...)
+
+	for _, field := range r.Required {
+		if value, _ := fieldValue(t, field); value == "" {
+			issues = append(issues, fmt.Sprintf("%s is empty", field))
+		}
+	}
+
+	if r.DateInFilingYear && filingYear > 0 && !t.TradeDate.IsZero() {
+		if year := t.TradeDate.Year(); year != filingYear && year != filingYear-1 {
+			issues = append(issues, fmt.Sprintf("date %s is not in filing year %d", t.Date, filingYear))
+		}
+	}
+
+	if r.FiledAfterDate && !t.TradeDate.IsZero() && !t.FiledDate.IsZero() && t.FiledDate.Before(t.TradeDate) {
+		issues = append(issues, fmt.Sprintf("filed %s before trade date %s", t.Filed, t.Date))
+	}
+
+	if r.KnownAmountBand && (t.AmountMin != 0 || t.AmountMax != 0) && !KnownBand(t.AmountMin, t.AmountMax) {
+		issues = append(issues, fmt.Sprintf("amount %q is not a disclosure band", t.Amount))
+	}
+
+	if r.TickerFormat != "" && t.Ticker != "" {
+		switch {
+		case r.tickerRe == nil:
+			issues = append(issues, fmt.Sprintf("ticker format %q was not compiled, see LoadRules", r.TickerFormat))
+		case !r.tickerRe.MatchString(t.Ticker):
+			issues = append(issues, fmt.Sprintf("ticker %q has an invalid format", t.Ticker))
+		}
+	}
+
+	return issues
+}
+
+// fieldValue returns the value of the field of t named field, and whether
+// there is such a field.
+func fieldValue(t Trade, field string) (string, bool) {
+	switch field {
+	case "Name":
+		return t.Name, true
+	case "Owner":
+		return t.Owner, true
+	case "Asset":
+		return t.Asset, true
+	case "Ticker":
+		return t.Ticker, true
+	case "Type":
+		return string(t.Type), true
+	case "Date":
+		return t.Date, true
+	case "Filed":
+		return t.Filed, true
+	case "Amount":
+		return t.Amount, true
+	case "AssetType":
+		return t.AssetType, true
+	}
+	return "", false
+}
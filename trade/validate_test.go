@@ -0,0 +1,78 @@
+package trade
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadRules(t *testing.T) {
+	dir := t.TempDir()
+	rules, err := LoadRules(filepath.Join(dir, FILE_RULES))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rules.Required, DefaultRules().Required) || !rules.DateInFilingYear {
+		t.Errorf("rules without file are %+v, want the defaults", rules)
+	}
+
+	// checks missing from the file keep their default
+	file := filepath.Join(dir, "partial.json")
+	if err := os.WriteFile(file, []byte(`{"Required": ["Name"], "TickerFormat": "^[A-Z]+$"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if rules, err = LoadRules(file); err != nil {
+		t.Fatal(err)
+	}
+	tr := Trade{Name: "A", Type: "P", Date: "01/10/2019", Filed: "01/02/2019", Amount: "$1,000 - $2,000"}
+	Normalize(&tr)
+	want := []string{
+		"date 01/10/2019 is not in filing year 2025",
+		"filed 01/02/2019 before trade date 01/10/2019",
+		`amount "$1,000 - $2,000" is not a disclosure band`,
+	}
+	if issues := rules.Validate(tr, 2025); !reflect.DeepEqual(issues, want) {
+		t.Errorf("issues\n%q\nwant\n%q", issues, want)
+	}
+	if def := DefaultRules(); def.Required[0] != "Name" || len(def.Required) != 6 {
+		t.Errorf("loading changed the default rules: %+v", def)
+	}
+}
+
+func TestLoadRulesInvalid(t *testing.T) {
+	tests := []struct {
+		name, rules, err string
+	}{
+		{"unknown required field", `{"Required": ["Nmae"]}`, `unknown required field "Nmae"`},
+		{"invalid ticker format", `{"TickerFormat": "[A-Z"}`, "invalid ticker format"},
+		{"invalid JSON", `{"Required": "Name"}`, "failed to read rules"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), FILE_RULES)
+			if err := os.WriteFile(file, []byte(tt.rules), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadRules(file); err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("got %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestValidateTickerFormat(t *testing.T) {
+	tr := Trade{Ticker: "nvda"}
+	rules := DefaultRules()
+	rules.Required = nil
+	if issues := rules.Validate(tr, 0); !reflect.DeepEqual(issues, []string{`ticker "nvda" has an invalid format`}) {
+		t.Errorf("got %q", issues)
+	}
+	if issues := rules.Validate(Trade{Ticker: "BRK.B"}, 0); len(issues) != 0 {
+		t.Errorf("got %q for a valid ticker", issues)
+	}
+	if issues := (Rules{TickerFormat: "^[A-Z]+$"}).Validate(tr, 0); len(issues) != 1 || !strings.Contains(issues[0], "not compiled") {
+		t.Errorf("ticker format of rules not built by LoadRules was not reported, got %q", issues)
+	}
+}
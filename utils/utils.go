@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"strings"
+	"path/filepath"
 )
 
 func ReadJSON[T any](file string) (T, error) {
@@ -17,6 +17,7 @@ func ReadJSON[T any](file string) (T, error) {
 			if err := os.WriteFile(file, []byte("[]"), 0644); err != nil {
 				return result, fmt.Errorf("failed to create file: %w", err)
 			}
+			return result, nil
 		} else {
 			return result, fmt.Errorf("failed to open file: %w", err)
 		}
@@ -36,16 +37,33 @@ func ReadJSON[T any](file string) (T, error) {
 	return result, nil
 }
 
+// WriteJSON writes data to a temporary file first and renames it over file,
+// so a crash mid-write never leaves a truncated file behind.
 func WriteJSON[T any](file string, data T) error {
 	bytes, err := json.MarshalIndent(data, "", "  ") // Pretty-print JSON
 	if err != nil {
 		return fmt.Errorf("failed to marshal struct: %w", err)
 	}
 
-	err = os.WriteFile(file, bytes, 0644)
+	// every writer has a temporary file of its own
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*.tmp")
 	if err != nil {
 		return fmt.Errorf("failed to write to file: %w", err)
 	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(bytes); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write to file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write to file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to write to file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return fmt.Errorf("failed to write to file: %w", err)
+	}
 	return nil
 }
 
@@ -58,26 +76,3 @@ func Contains(slice []string, str string) bool {
 	}
 	return false
 }
-
-func EnsureValidJSON(input string) (string, error) {
-	input = strings.TrimSpace(input)
-	if !strings.HasSuffix(input, "}") && !strings.HasSuffix(input, "]") {
-		input = input + "}"
-	}
-	var temp interface{}
-	if err := json.Unmarshal([]byte(input), &temp); err != nil {
-		return "", fmt.Errorf("invalid JSON format: %w", err)
-	}
-	return input, nil
-}
-
-func SafeUnmarshal(out string, target interface{}) error {
-	validJSON, err := EnsureValidJSON(out)
-	if err != nil {
-		return fmt.Errorf("failed to ensure valid JSON: %v, output: %s", err, out)
-	}
-	if err := json.Unmarshal([]byte(validJSON), target); err != nil {
-		return fmt.Errorf("failed to unmarshall JSON: %v, output: %s", err, validJSON)
-	}
-	return nil
-}
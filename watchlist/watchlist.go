@@ -0,0 +1,101 @@
+package watchlist
+
+import (
+	"clerk_trades/clerk"
+	"clerk_trades/names"
+	"clerk_trades/trade"
+	"fmt"
+	"strings"
+)
+
+// Watchlist names the members and tickers someone wants to be notified
+// about. Every watchlist keeps its own record of the trades it was sent.
+type Watchlist struct {
+	Name    string   `yaml:"name"`
+	Members []string `yaml:"members"` // member IDs, or names in any order, e.g. "Nancy Pelosi" or "Pelosi, Nancy"
+	Tickers []string `yaml:"tickers"`
+
+	ids map[string]string // member ID of each resolved member
+}
+
+// Resolve looks up the member ID of every member with resolve, which
+// returns "" for unknown members.
+func (w *Watchlist) Resolve(resolve func(name string) string) {
+	w.ids = make(map[string]string)
+	for _, m := range w.Members {
+		if id := resolve(m); id != "" {
+			w.ids[m] = id
+		}
+	}
+}
+
+// Member reports whether the member with ID id, filing as name, is one of
+// the members of w. Resolved members are compared by ID; names of the others,
+// and names without ID, match when all words of the watched name appear in
+// them, see names.Match.
+func (w Watchlist) Member(id, name string) bool {
+	for _, m := range w.Members {
+		if watched := w.ids[m]; watched != "" && id != "" {
+			if watched == id {
+				return true
+			}
+			continue
+		}
+		if names.Match(m, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Ticker reports whether ticker is one of the tickers of w.
+func (w Watchlist) Ticker(ticker string) bool {
+	ticker = strings.TrimSpace(ticker)
+	for _, t := range w.Tickers {
+		if ticker != "" && strings.EqualFold(t, ticker) {
+			return true
+		}
+	}
+	return false
+}
+
+// Watches reports whether w watches trade t of report r: all trades of a
+// watched member, and trades of watched tickers by anyone.
+func (w Watchlist) Watches(r clerk.Report, t trade.Trade) bool {
+	return w.Member(r.MemberID, r.Name) || w.Member(t.MemberID, t.Name) || w.Ticker(t.Ticker)
+}
+
+// Wants reports whether report r has to be processed for any of lists.
+// Without watchlists every report is wanted. Reports of unwatched members
+// are only wanted if a watchlist has tickers, which any report may trade.
+// Reports of unknown filers, like local files, are wanted, as only their
+// trades tell who filed them.
+func Wants(lists []Watchlist, r clerk.Report) bool {
+	if len(lists) == 0 || r.Name == "" && r.MemberID == "" {
+		return true
+	}
+	for _, w := range lists {
+		if len(w.Tickers) > 0 || w.Member(r.MemberID, r.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that every watchlist has a unique name and watches
+// something.
+func Validate(lists []Watchlist) error {
+	seen := make(map[string]bool)
+	for i, w := range lists {
+		switch {
+		case strings.TrimSpace(w.Name) == "":
+			return fmt.Errorf("watchlist %d has no name", i+1)
+		case seen[w.Name]:
+			return fmt.Errorf("watchlist %q is defined twice", w.Name)
+		case len(w.Members) == 0 && len(w.Tickers) == 0:
+			return fmt.Errorf("watchlist %q has no members or tickers", w.Name)
+		}
+		seen[w.Name] = true
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package watchlist
+
+import (
+	"clerk_trades/clerk"
+	"clerk_trades/trade"
+	"testing"
+)
+
+func TestMember(t *testing.T) {
+	w := Watchlist{Name: "leadership", Members: []string{"Nancy Pelosi", "Jordan, Jim", "K000389"}}
+	ids := map[string]string{"Nancy Pelosi": "P000197", "Jordan, Jim": "J000289"}
+	w.Resolve(func(name string) string { return ids[name] })
+
+	tests := []struct {
+		name   string
+		id     string
+		filer  string
+		member bool
+	}{
+		{"resolved by ID", "P000197", "Pelosi, Nancy", true},
+		{"ID wins over the name", "P000198", "Pelosi, Nancy", false},
+		{"name without ID", "", "Hon. Nancy Pelosi", true},
+		{"accents and order", "", "Jórdan, Jim", true},
+		{"other member", "", "Pelosi, Paul", false},
+		{"unresolved member matches by name", "", "K000389", true},
+		{"unresolved member with ID", "K000389", "Khanna, Ro", false},
+		{"nobody", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := w.Member(tt.id, tt.filer); got != tt.member {
+				t.Errorf("Member(%q, %q) = %v, want %v", tt.id, tt.filer, got, tt.member)
+			}
+		})
+	}
+}
+
+func TestWatches(t *testing.T) {
+	w := Watchlist{Name: "chips", Members: []string{"Nancy Pelosi"}, Tickers: []string{"NVDA"}}
+	w.Resolve(func(string) string { return "" })
+	pelosi := clerk.Report{Name: "Pelosi, Nancy"}
+	other := clerk.Report{Name: "Doe, John"}
+
+	tests := []struct {
+		name    string
+		r       clerk.Report
+		t       trade.Trade
+		watches bool
+	}{
+		{"member of the report", pelosi, trade.Trade{Ticker: "AAPL"}, true},
+		{"member of the trade", other, trade.Trade{Name: "Nancy Pelosi", Ticker: "AAPL"}, true},
+		{"ticker", other, trade.Trade{Ticker: " nvda"}, true},
+		{"neither", other, trade.Trade{Ticker: "AAPL"}, false},
+		{"no ticker", other, trade.Trade{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := w.Watches(tt.r, tt.t); got != tt.watches {
+				t.Errorf("watches %v, want %v", got, tt.watches)
+			}
+		})
+	}
+}
+
+func TestWants(t *testing.T) {
+	members := []Watchlist{{Name: "leadership", Members: []string{"Nancy Pelosi"}}}
+	tickers := append(members, Watchlist{Name: "chips", Tickers: []string{"NVDA"}})
+	pelosi, other := clerk.Report{Name: "Pelosi, Nancy"}, clerk.Report{Name: "Doe, John"}
+
+	if !Wants(nil, other) {
+		t.Error("every report is wanted without watchlists")
+	}
+	if !Wants(members, pelosi) || Wants(members, other) {
+		t.Error("only reports of watched members are wanted without tickers")
+	}
+	if !Wants(tickers, other) {
+		t.Error("every report is wanted with tickers")
+	}
+	if !Wants(members, clerk.Report{URL: "file:///tmp/report.pdf"}) {
+		t.Error("report of an unknown filer is not wanted")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		lists []Watchlist
+		ok    bool
+	}{
+		{"valid", []Watchlist{{Name: "a", Members: []string{"Nancy Pelosi"}}, {Name: "b", Tickers: []string{"NVDA"}}}, true},
+		{"no name", []Watchlist{{Members: []string{"Nancy Pelosi"}}}, false},
+		{"twice", []Watchlist{{Name: "a", Tickers: []string{"NVDA"}}, {Name: "a", Tickers: []string{"AAPL"}}}, false},
+		{"empty", []Watchlist{{Name: "a"}}, false},
+	}
+	for _, tt := range tests {
+		if err := Validate(tt.lists); (err == nil) != tt.ok {
+			t.Errorf("%s: got %v", tt.name, err)
+		}
+	}
+}